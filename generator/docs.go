@@ -0,0 +1,311 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/parser"
+	"github.com/mllrjb/hackathon-go-openapi-v3/templates"
+)
+
+// docsTemplate renders the HTML reference page from a DocData. Unlike the
+// other templates it is executed with html/template, so anything taken from
+// the spec is escaped for the context it appears in.
+const docsTemplate = "docs.html.tmpl"
+
+// DocData is passed to docs.html.tmpl.
+type DocData struct {
+	Title       string
+	Version     string
+	Description string
+	// Tags groups the operations by their first tag, in the order the tags
+	// are first used. Untagged operations are grouped under "default".
+	Tags []*DocTag
+	// Schemas are the component schemas, sorted by name.
+	Schemas []*DocSchema
+}
+
+// DocTag is a group of operations sharing a tag.
+type DocTag struct {
+	Name       string
+	Operations []*DocOperation
+}
+
+// DocOperation is a single operation on the reference page.
+type DocOperation struct {
+	// Anchor is the id of the operation's element on the page.
+	Anchor      string
+	OperationID string
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Deprecated  bool
+	// Security describes the requirements, e.g. "oauth (read) or apiKey".
+	Security   string
+	Parameters []*DocParameter
+	Requests   []*DocBody
+	Responses  []*DocResponse
+}
+
+// DocParameter is a parameter of an operation, or a header of a response.
+type DocParameter struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Deprecated  bool
+	Schema      *DocSchema
+}
+
+// DocBody is a request body for one content type.
+type DocBody struct {
+	ContentType string
+	Schema      *DocSchema
+}
+
+// DocResponse is a response for one status code and content type.
+type DocResponse struct {
+	StatusCode  string
+	Description string
+	ContentType string
+	Schema      *DocSchema
+	Headers     []*DocParameter
+}
+
+// DocSchema describes a schema. References to components are not expanded;
+// they only carry the component's Name and Anchor so that the page links to
+// it, which also keeps recursive schemas finite.
+type DocSchema struct {
+	// Name is the component name, empty for inline schemas.
+	Name string
+	// Anchor is the id of the component's element on the page.
+	Anchor string
+	// IsRef is set when the schema is a reference to the component Name.
+	IsRef       bool
+	Type        string
+	Format      string
+	Description string
+	Deprecated  bool
+	Nullable    bool
+	Properties  []*DocProperty
+	Items       *DocSchema
+	// Variant is oneOf or anyOf, with Variants listing the alternatives.
+	Variant       string
+	Variants      []*DocSchema
+	Discriminator string
+}
+
+// DocProperty is a property of an object schema.
+type DocProperty struct {
+	Name     string
+	Required bool
+	Schema   *DocSchema
+}
+
+// generateDocs renders docs.html.tmpl, from overrideDir if it has a copy,
+// into outputDir/docs.html.
+func generateDocs(walker parser.Walker, overrideDir string, outputDir string) error {
+	content, err := templates.FS.ReadFile(docsTemplate)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %v", docsTemplate, err)
+	}
+	if len(overrideDir) > 0 {
+		override, err := ioutil.ReadFile(filepath.Join(overrideDir, docsTemplate))
+		if err == nil {
+			content = override
+		}
+	}
+
+	t, err := htmltemplate.New(docsTemplate).Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %v", docsTemplate, err)
+	}
+
+	var buf bytes.Buffer
+	err = t.Execute(&buf, GenerateDocData(walker))
+	if err != nil {
+		return fmt.Errorf("error processing %s: %v", docsTemplate, err)
+	}
+
+	return writeFile(fmt.Sprintf("%s/docs.html", outputDir), buf.Bytes())
+}
+
+// GenerateDocData builds the reference page's data from the parsed spec.
+func GenerateDocData(walker parser.Walker) DocData {
+	info := walker.GetInfo()
+	data := DocData{
+		Title:       info.Title,
+		Version:     info.Version,
+		Description: info.Description,
+	}
+
+	tags := map[string]*DocTag{}
+	for _, op := range walker.GetOperations() {
+		name := "default"
+		if len(op.Tags) > 0 {
+			name = op.Tags[0]
+		}
+
+		tag, ok := tags[name]
+		if !ok {
+			tag = &DocTag{Name: name}
+			tags[name] = tag
+			data.Tags = append(data.Tags, tag)
+		}
+		tag.Operations = append(tag.Operations, docOperation(op))
+	}
+
+	for _, model := range walker.GetModels() {
+		data.Schemas = append(data.Schemas, docSchema(model, true))
+	}
+	sort.Slice(data.Schemas, func(i, j int) bool {
+		return data.Schemas[i].Name < data.Schemas[j].Name
+	})
+
+	return data
+}
+
+func docOperation(op *parser.Operation) *DocOperation {
+	doc := &DocOperation{
+		Anchor:      "operation-" + op.OperationID,
+		OperationID: op.OperationID,
+		Method:      op.Method,
+		Path:        op.Path,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Deprecated:  op.Deprecated,
+		Security:    docSecurity(op.Security),
+	}
+
+	for _, p := range op.Parameters {
+		doc.Parameters = append(doc.Parameters, &DocParameter{
+			Name:        p.Name,
+			In:          p.In,
+			Description: p.Description,
+			Required:    p.Required,
+			Deprecated:  p.Deprecated,
+			Schema:      docSchema(p.Schema, false),
+		})
+	}
+
+	for _, r := range op.Requests {
+		doc.Requests = append(doc.Requests, &DocBody{
+			ContentType: r.Accept,
+			Schema:      docSchema(r.Body, false),
+		})
+	}
+
+	for _, r := range op.Responses {
+		response := &DocResponse{
+			StatusCode:  r.StatusCode,
+			Description: r.Description,
+			ContentType: r.ContentType,
+			Schema:      docSchema(r.Body, false),
+		}
+		for _, h := range r.Headers {
+			response.Headers = append(response.Headers, &DocParameter{
+				Name:        h.Name,
+				In:          "header",
+				Description: h.Description,
+				Required:    h.Required,
+				Deprecated:  h.Deprecated,
+				Schema:      docSchema(h.Schema, false),
+			})
+		}
+		doc.Responses = append(doc.Responses, response)
+	}
+
+	return doc
+}
+
+// docSecurity describes an operation's security requirements, or returns
+// "" if it has none.
+func docSecurity(requirements []parser.SecurityRequirement) string {
+	var alternatives []string
+	for _, requirement := range requirements {
+		var schemes []string
+		for scheme, scopes := range requirement {
+			if len(scopes) > 0 {
+				scheme = fmt.Sprintf("%s (%s)", scheme, strings.Join(scopes, ", "))
+			}
+			schemes = append(schemes, scheme)
+		}
+		sort.Strings(schemes)
+		if len(schemes) > 0 {
+			alternatives = append(alternatives, strings.Join(schemes, " and "))
+		}
+	}
+	return strings.Join(alternatives, " or ")
+}
+
+// docSchema describes model. Components are only expanded when expand is
+// set, i.e. for the component's own entry in DocData.Schemas.
+func docSchema(model parser.SchemaModel, expand bool) *DocSchema {
+	if model == nil {
+		return nil
+	}
+
+	doc := &DocSchema{
+		Type:        model.GetType(),
+		Description: model.GetDescription(),
+		Deprecated:  model.IsDeprecated(),
+	}
+	if model.IsComponent() {
+		doc.Name = model.GetComponentName()
+		doc.Anchor = "schema-" + doc.Name
+		if !expand {
+			doc.IsRef = true
+			return doc
+		}
+	}
+
+	switch m := model.(type) {
+	case *parser.StructSchemaModel:
+		doc.Nullable = m.Nullable
+		names := make([]string, 0, len(m.Properties))
+		for name := range m.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			doc.Properties = append(doc.Properties, &DocProperty{
+				Name:     name,
+				Required: contains(m.Required, name),
+				Schema:   docSchema(m.Properties[name], false),
+			})
+		}
+	case *parser.ArraySchemaModel:
+		doc.Nullable = m.Nullable
+		doc.Items = docSchema(m.Items, false)
+	case *parser.PrimitiveSchemaModel:
+		doc.Nullable = m.Nullable
+		doc.Format = m.Format
+	}
+
+	if d := model.GetDiscriminator(); d != nil && d.IsDiscriminated() {
+		doc.Variant = d.DiscriminatorType
+		for _, variant := range d.DiscriminatorSchemas {
+			doc.Variants = append(doc.Variants, docSchema(variant, false))
+		}
+		if d.Discriminator != nil {
+			doc.Discriminator = d.Discriminator.PropertyName
+		}
+	}
+
+	return doc
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -4,21 +4,35 @@ import (
 	"bytes"
 	"fmt"
 	"go/format"
-	"html/template"
 	"io"
-	"io/ioutil"
 	"os"
 	"path"
 	"strings"
+	"text/template"
 
 	"github.com/mllrjb/hackathon-go-openapi-v3/utils"
 
 	"github.com/mllrjb/hackathon-go-openapi-v3/parser"
 )
 
-const templateDir = "./templates"
-const outputDir = "generated"
-const formatSource = false
+const formatSource = true
+
+// width that doc comments are wrapped to, not counting the "// " prefix
+const docWidth = 76
+
+// Config controls where GenerateFiles reads templates from and where it
+// writes the generated packages.
+type Config struct {
+	// TemplateDir optionally overrides the embedded default templates.
+	TemplateDir string
+	// OutputDir is the directory the generated packages are written to.
+	OutputDir string
+	// PackagePath is the import path of OutputDir.
+	PackagePath string
+	// SplitByTag generates one interface per OpenAPI tag, which
+	// ServerInterface then embeds.
+	SplitByTag bool
+}
 
 func ref(gs *GenSchema, currentPackage string) string {
 	if gs.IsDefinedElsewhere {
@@ -46,50 +60,96 @@ func ref(gs *GenSchema, currentPackage string) string {
 	return "UNKNOWN_REF_TYPE"
 }
 
-func GenerateFiles(walker parser.Walker) {
-	files, err := ioutil.ReadDir(templateDir)
-	if err != nil {
-		fmt.Printf("unable to read template directory: %v", err)
-		os.Exit(1)
+// goLiteral renders a value decoded from the spec, such as an enum value,
+// as a Go expression of type interface{}. Numbers are float64, as
+// encoding/json decodes them.
+func goLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return utils.GoString(v)
+	case bool:
+		return fmt.Sprint(v)
+	case int:
+		return fmt.Sprintf("float64(%d)", v)
+	case int64:
+		return fmt.Sprintf("float64(%d)", v)
+	case uint64:
+		return fmt.Sprintf("float64(%d)", v)
+	case float64:
+		return fmt.Sprintf("float64(%v)", v)
 	}
+	return utils.GoString(fmt.Sprint(v))
+}
 
-	err = os.MkdirAll(outputDir, os.ModePerm)
-	if err != nil {
-		fmt.Printf("unable to create output dir: %v", err)
-		os.Exit(1)
+// docComment renders each non-empty paragraph as a wrapped Go comment,
+// followed by a "Deprecated:" paragraph if needed, so that linters flag use
+// of deprecated types and handlers.
+func docComment(deprecated bool, paragraphs ...string) string {
+	var parts []string
+	for _, p := range paragraphs {
+		p = strings.TrimSpace(p)
+		if len(p) > 0 {
+			parts = append(parts, utils.WrapText(p, docWidth))
+		}
+	}
+	if deprecated {
+		parts = append(parts, "Deprecated: marked as deprecated in the API specification.")
 	}
 
-	var templateFiles []string
-	for _, file := range files {
-		filename := file.Name()
-		if strings.HasSuffix(filename, ".tmpl") {
-			templateFiles = append(templateFiles, fmt.Sprintf("%v/%v", templateDir, filename))
-		} else if strings.HasSuffix(filename, ".go") {
-			err = copyFile(fmt.Sprintf("%s/%s", templateDir, filename), fmt.Sprintf("%s/%s", outputDir, filename))
-			if err != nil {
-				fmt.Printf("error copying .go file: %v", err)
-				os.Exit(1)
-			}
-			fmt.Printf("copied file %v\n", filename)
-		}
+	if len(parts) == 0 {
+		return ""
 	}
+	return utils.GoComment(strings.Join(parts, "\n\n")) + "\n"
+}
 
-	funcMap := template.FuncMap{
+// templateFuncs are the functions available to every template.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
 		"Title":  strings.Title,
+		"lower":  strings.ToLower,
 		"pascal": utils.ToPascalCase,
 		"ref":    ref,
+		"xmlTag": xmlTag,
+
+		// text/template does no escaping of its own, so anything that comes
+		// from the spec must go through one of these before it is embedded
+		// in generated source
+		"goString":  utils.GoString,
+		"goComment": utils.GoComment,
+		"structTag": utils.StructTag,
+		"literal":   goLiteral,
+		"doc":       docComment,
+
+		// the same for TypeScript, see typescript.go
+		"tsString":   tsString,
+		"tsDoc":      tsDoc,
+		"tsKey":      tsKey,
+		"tsRef":      tsRef,
+		"tsType":     tsDeclaration,
+		"tsObject":   tsObject,
+		"tsStatus":   tsStatus,
+		"tsBody":     tsResponseBody,
+		"tsKind":     tsResponseKind,
+		"lowerFirst": lowerFirst,
+
+		// and for protobuf, see proto.go
+		"protoString":      protoString,
+		"protoComment":     protoComment,
+		"protoMessage":     protoMessage,
+		"protoToMessage":   protoToMessage,
+		"protoFromMessage": protoFromMessage,
 	}
+}
 
-	t := template.New("template").Funcs(funcMap)
-	t, err = t.ParseFiles(templateFiles...)
-	if err != nil {
-		fmt.Printf("unable to parse template files: %v", err)
-		os.Exit(1)
-	}
-
+// templateData builds the model of the spec that the templates of every
+// output language are executed with.
+func templateData(walker parser.Walker, config Config) TemplateData {
 	genOps := []*GenOperation{}
 	for _, op := range walker.GetOperations() {
 		genOp := GenerateOperation(op)
+		genOp.Imports = operationImports(&genOp, config.PackagePath)
 		genOps = append(genOps, &genOp)
 	}
 
@@ -102,20 +162,66 @@ func GenerateFiles(walker parser.Walker) {
 		nested := GetAllNestedModels(&gs)
 		genSchemas = append(genSchemas, nested...)
 	}
+	setDiscriminatorValues(genSchemas)
+
+	genSchemes := []GenSecurityScheme{}
+	for _, scheme := range walker.GetSecuritySchemes() {
+		genSchemes = append(genSchemes, GenerateSecurityScheme(scheme))
+	}
+
+	return TemplateData{
+		PackagePath:     config.PackagePath,
+		Operations:      genOps,
+		Schemas:         genSchemas,
+		Imports:         routingImports(genOps, config.PackagePath),
+		SecuritySchemes: genSchemes,
+		SplitByTag:      config.SplitByTag,
+		ServerGroups:    serverGroups(genOps, config.SplitByTag),
+	}
+}
+
+func GenerateFiles(walker parser.Walker, config Config) {
+	t, err := LoadTemplates(config.TemplateDir, templateFuncs())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	err = os.MkdirAll(config.OutputDir, os.ModePerm)
+	if err != nil {
+		fmt.Printf("unable to create output dir: %v", err)
+		os.Exit(1)
+	}
+
+	data := templateData(walker, config)
+	genOps := data.Operations
+	genSchemas := data.Schemas
 
-	generateOperations(t, genOps)
-	generateComponents(t, genSchemas)
-	generatePaths(t, genOps)
+	generateOperations(t.Template, config.OutputDir, genOps)
+	generateComponents(t.Template, config.OutputDir, genSchemas)
+	generateSupportFiles(t, config.OutputDir, data)
 
+	err = generateSpec(walker, config.OutputDir)
+	if err != nil {
+		fmt.Printf("unable to write spec: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = generateDocs(walker, config.TemplateDir, config.OutputDir)
+	if err != nil {
+		fmt.Printf("unable to write docs: %v\n", err)
+		os.Exit(1)
+	}
 }
 
 func writeFile(filepath string, bytes []byte) error {
-	if formatSource {
+	if formatSource && strings.HasSuffix(filepath, ".go") {
 		formattedBytes, err := format.Source(bytes)
 		if err != nil {
 			fmt.Printf("warning: unable to format output for %s: %v\n", filepath, err)
+		} else {
+			bytes = formattedBytes
 		}
-		bytes = formattedBytes
 	}
 
 	filedir := path.Dir(filepath)
@@ -137,7 +243,7 @@ func writeFile(filepath string, bytes []byte) error {
 	return nil
 }
 
-func generateOperations(tmpl *template.Template, genOps []*GenOperation) {
+func generateOperations(tmpl *template.Template, outputDir string, genOps []*GenOperation) {
 	otmpl := tmpl.Lookup("operation.tmpl")
 	if otmpl == nil {
 		fmt.Println("could not find operation template")
@@ -162,7 +268,7 @@ func generateOperations(tmpl *template.Template, genOps []*GenOperation) {
 	}
 }
 
-func generateComponents(tmpl *template.Template, genSchemas []*GenSchema) {
+func generateComponents(tmpl *template.Template, outputDir string, genSchemas []*GenSchema) {
 	ctmpl := tmpl.Lookup("components.tmpl")
 	if ctmpl == nil {
 		fmt.Println("could not find components template")
@@ -185,39 +291,38 @@ func generateComponents(tmpl *template.Template, genSchemas []*GenSchema) {
 	}
 }
 
-func generatePaths(tmpl *template.Template, genOps []*GenOperation) {
-	ptmpl := tmpl.Lookup("pathRouting.tmpl")
-	if ptmpl == nil {
-		fmt.Println("could not find operation template")
-		os.Exit(1)
-	}
+func generateSupportFiles(tmpls *Templates, outputDir string, data TemplateData) {
+	for _, sf := range supportFiles {
+		outPath := fmt.Sprintf("%s/%s", outputDir, sf.Output)
 
-	var buf bytes.Buffer
-	err := ptmpl.Execute(&buf, genOps)
-	if err != nil {
-		fmt.Printf("error processing operation: %v\n", err)
-		os.Exit(1)
-	}
+		if src, ok := tmpls.Verbatim[sf.Template]; ok {
+			err := copyFile(src, outPath)
+			if err != nil {
+				fmt.Printf("error copying .go file: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("copied file %v\n", src)
+			continue
+		}
 
-	// fmt.Println(buf.String())
-	formattedBytes := buf.Bytes()
-	// formattedBytes, err := format.Source(buf.Bytes())
-	if err != nil {
-		fmt.Printf("error formatting pathRouting: %v\n", err)
-		os.Exit(1)
-	}
+		stmpl := tmpls.Lookup(sf.Template)
+		if stmpl == nil {
+			fmt.Printf("could not find %s template\n", sf.Template)
+			os.Exit(1)
+		}
 
-	opPath := fmt.Sprintf("%s/pathRouting.go", outputDir)
-	opFile, err := os.Create(opPath)
-	if err != nil {
-		fmt.Printf("unable to create %s: %v", opPath, err)
-		os.Exit(1)
-	}
+		var buf bytes.Buffer
+		err := stmpl.Execute(&buf, data)
+		if err != nil {
+			fmt.Printf("error processing %s: %v\n", sf.Template, err)
+			os.Exit(1)
+		}
 
-	_, err = opFile.Write(formattedBytes)
-	if err != nil {
-		fmt.Printf("unable to write to %s: %v", opPath, err)
-		os.Exit(1)
+		err = writeFile(outPath, buf.Bytes())
+		if err != nil {
+			fmt.Printf("unable to write %s: %v\n", sf.Output, err)
+			os.Exit(1)
+		}
 	}
 }
 
@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	openapi_v3 "github.com/googleapis/gnostic/OpenAPIv3"
+	"github.com/googleapis/gnostic/compiler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/parser"
+)
+
+// walk parses and traverses a spec written inline in a test, with tabs for
+// indentation so it can be lined up with the code.
+func walk(t *testing.T, spec string) parser.Walker {
+	var info yaml.MapSlice
+	require.NoError(t, yaml.Unmarshal([]byte(strings.ReplaceAll(spec, "\t", "  ")), &info))
+	document, err := openapi_v3.NewDocument(info, compiler.NewContext("$root", nil))
+	require.NoError(t, err)
+
+	w := parser.NewWalker(document)
+	w.SetSource(info)
+	require.NoError(t, w.Traverse())
+	return w
+}
+
+// readOutput reads a generated file.
+func readOutput(t *testing.T, dir string, name string) string {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	require.NoError(t, err)
+	return string(b)
+}
+
+// between is the part of s from the first start to the end that follows it.
+func between(s string, start string, end string) string {
+	i := strings.Index(s, start)
+	if i < 0 {
+		return ""
+	}
+	s = s[i:]
+	return s[:strings.Index(s, end)+len(end)]
+}
+
+func TestGenerateHandlers(t *testing.T) {
+	w := walk(t, `
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+	/pets:
+		post:
+			operationId: addPet
+			requestBody:
+				content:
+					application/json: {schema: {$ref: "#/components/schemas/Pet"}}
+			responses:
+				"201": {description: created}
+components:
+	schemas:
+		Pet: {type: object, properties: {name: {type: string}}}
+`)
+
+	dir := t.TempDir()
+	GenerateFiles(w, Config{OutputDir: dir, PackagePath: "example.com/api"})
+
+	// the body is only referred to by the server interface
+	op := readOutput(t, dir, "operation/AddPet.go")
+	assert.NotContains(t, op, `"example.com/api/component"`)
+	assert.NotContains(t, op, "AddPetHandler")
+
+	server := readOutput(t, dir, "server.go")
+	assert.Contains(t, server, `"example.com/api/component"`)
+	assert.Contains(t, server, "AddPet(ctx context.Context, params operation.AddPetParameters, body component.Pet) (operation.Responder, error)")
+}
+
+func TestGenerateFormBodies(t *testing.T) {
+	w := walk(t, `
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+	/files:
+		post:
+			operationId: upload
+			requestBody:
+				content:
+					multipart/form-data:
+						schema:
+							type: object
+							properties:
+								photo: {type: string, format: binary}
+								attachments: {type: array, items: {type: string, format: binary}}
+					application/x-www-form-urlencoded:
+						schema:
+							type: object
+							properties:
+								photo: {type: string, format: binary}
+			responses:
+				"204": {description: uploaded}
+`)
+
+	dir := t.TempDir()
+	GenerateFiles(w, Config{OutputDir: dir, PackagePath: "example.com/api"})
+
+	op := readOutput(t, dir, "operation/Upload.go")
+	assert.Regexp(t, `Photo\s+\*FilePart`, between(op, "type UploadMultipart struct", "}"))
+	assert.Regexp(t, `Attachments\s+\*FileParts`, between(op, "type UploadMultipart struct", "}"))
+	assert.Regexp(t, `Photo\s+string`, between(op, "type UploadForm struct", "}"))
+}
+
+func TestGenerateFormBodyRefs(t *testing.T) {
+	w := walk(t, `
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+	/pets:
+		post:
+			operationId: addPet
+			requestBody:
+				content:
+					application/x-www-form-urlencoded:
+						schema:
+							type: object
+							properties:
+								name: {type: string}
+								owner: {$ref: "#/components/schemas/Owner"}
+			responses:
+				"201": {description: created}
+components:
+	schemas:
+		Owner: {type: object, properties: {name: {type: string}}}
+`)
+
+	dir := t.TempDir()
+	GenerateFiles(w, Config{OutputDir: dir, PackagePath: "example.com/api"})
+
+	op := readOutput(t, dir, "operation/AddPet.go")
+	assert.Contains(t, op, `"example.com/api/component"`)
+	assert.Regexp(t, `Owner\s+component\.Owner\s`, between(op, "type AddPetForm struct", "}"))
+}
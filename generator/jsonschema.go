@@ -0,0 +1,37 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/parser"
+)
+
+// GenerateJSONSchemas writes each component schema as a JSON Schema
+// document named after it, e.g. Pet.json, to outputDir. See
+// parser.Walker.JSONSchemas for direction.
+func GenerateJSONSchemas(walker parser.Walker, outputDir string, direction string) ([]parser.Diagnostic, error) {
+	documents, diags := walker.JSONSchemas(direction)
+	for _, document := range documents {
+		err := writeJSON(fmt.Sprintf("%s/%s.json", outputDir, document.Key), document.Value)
+		if err != nil {
+			return diags, err
+		}
+	}
+	return diags, nil
+}
+
+// GenerateJSONSchemaBundle writes every component schema to filepath as a
+// single JSON Schema document, with the schemas in $defs.
+func GenerateJSONSchemaBundle(walker parser.Walker, filepath string, direction string) ([]parser.Diagnostic, error) {
+	bundle, diags := walker.JSONSchemaBundle(direction)
+	return diags, writeJSON(filepath, bundle)
+}
+
+func writeJSON(filepath string, value interface{}) error {
+	jsonBytes, err := json.MarshalIndent(jsonValue(value), "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode %s as JSON: %v", filepath, err)
+	}
+	return writeFile(filepath, append(jsonBytes, '\n'))
+}
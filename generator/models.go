@@ -1,15 +1,326 @@
 package generator
 
+import "strings"
+
+// The types in this file make up the data model exposed to templates.
+//
+//   operation.tmpl      executes once per operation with a *GenOperation
+//   components.tmpl     executes once per component with a *GenSchema
+//   schema.tmpl         renders a single *GenSchema as a Go type expression
+//   pathRouting.tmpl,
+//   server.tmpl,
+//   middleware.tmpl,
+//   security.tmpl,
+//   router.go.tmpl,
+//   responder.go.tmpl,
+//   binder.go.tmpl,
+//   form.go.tmpl,
+//   problem.go.tmpl,
+//   spec.go.tmpl,
+//   validation.go.tmpl,
+//   mock.go.tmpl,
+//   arbitrary.go.tmpl,
+//   types.ts.tmpl,
+//   client.ts.tmpl      execute once per spec with a TemplateData
+//   docs.html.tmpl      executes once per spec with a DocData (see docs.go)
+
+// TemplateData is passed to the templates that are rendered once per spec.
+type TemplateData struct {
+	// PackagePath is the import path of the generated root package, e.g.
+	// "github.com/me/api/generated". Sub-packages live beneath it.
+	PackagePath string
+	// Operations holds every operation in the spec.
+	Operations []*GenOperation
+	// Schemas holds every component schema, plus named nested models.
+	Schemas []*GenSchema
+	// Imports are the generated packages pathRouting.tmpl depends on.
+	Imports []string
+	// SecuritySchemes are the entries of components.securitySchemes.
+	SecuritySchemes []GenSecurityScheme
+	// SplitByTag is set when ServerInterface is split into one interface
+	// per tag.
+	SplitByTag bool
+	// ServerGroups are the interfaces ServerInterface is made of. There is a
+	// single group with an empty Name unless SplitByTag is set.
+	ServerGroups []*GenServerGroup
+}
+
+// HasRequestBodies reports whether any operation takes a request body.
+func (d TemplateData) HasRequestBodies() bool {
+	for _, op := range d.Operations {
+		for _, h := range op.Handlers {
+			if h.Body != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OperationModels returns the models that operation.tmpl declares for
+// every operation, once each by name, in the order of the operations.
+func (d TemplateData) OperationModels() []*GenSchema {
+	models := []*GenSchema{}
+	seen := map[string]bool{}
+	for _, op := range d.Operations {
+		for _, m := range op.Models {
+			if !m.IsDefinedElsewhere && !seen[m.ReceiverName] {
+				seen[m.ReceiverName] = true
+				models = append(models, m)
+			}
+		}
+	}
+	return models
+}
+
+// GenServerGroup is one of the interfaces embedded in ServerInterface.
+type GenServerGroup struct {
+	// Name of the interface, e.g. CasesServer. Empty if not split by tag.
+	Name string
+	// Tag is the OpenAPI tag the group was built from.
+	Tag        string
+	Operations []*GenOperation
+}
+
+// GenOperation is a single OpenAPI operation (one path + method).
 type GenOperation struct {
-	Name     string
+	// OperationID is the operationId as written in the spec.
+	OperationID string
+	// Name is the PascalCase operationId.
+	Name string
+	// Summary and Description are copied from the spec as-is.
+	Summary     string
+	Description string
+	// Deprecated is set for `deprecated: true` operations.
+	Deprecated bool
+	// Tags are the operation's OpenAPI tags.
+	Tags []string
+	// Security lists alternative requirements, any one of which must be
+	// met. It is empty if the operation doesn't require authentication.
+	Security []GenSecurityRequirement
+	// Handlers has one entry per request media type, or a single entry
+	// without a Body if the operation takes no request body.
 	Handlers []GenHandler
-	Models   []*GenSchema
-	Path     string
-	Method   string
+	// Models are inline request, response and parameter schemas that need
+	// a named type in the operation package.
+	Models []*GenSchema
+	// Parameters are the fields of the operation's parameters struct.
+	Parameters []GenParameter
+	// Responses has one entry per status code and media type.
+	Responses []GenResponse
+	// ResponseHeaders has one entry per status code that declares headers.
+	ResponseHeaders []GenResponseHeaders
+	// NegotiatedResponses has one entry per status code that offers the
+	// same body in several media types, chosen by the Accept header.
+	NegotiatedResponses []GenNegotiatedResponse
+	// Path is the path template, e.g. /cases/{id}.
+	Path string
+	// Method is the upper case HTTP method.
+	Method string
+	// Imports are the generated packages the operation file depends on.
+	Imports []string
 }
 
+// HasRequiredParameters reports whether any parameter is required.
+func (op *GenOperation) HasRequiredParameters() bool {
+	for _, p := range op.Parameters {
+		if p.Required {
+			return true
+		}
+	}
+	return false
+}
+
+// HasBinaryResponses reports whether any response is streamed from an
+// io.Reader.
+func (op *GenOperation) HasBinaryResponses() bool {
+	for _, r := range op.Responses {
+		if r.IsBinary {
+			return true
+		}
+	}
+	return false
+}
+
+// GenHandler is the handler interface generated for one request media type.
 type GenHandler struct {
-	Name   string
+	// MethodName is the ServerInterface method, e.g. UpdateCase_VndCaseV1,
+	// or just the operation name if the media type has no title (JSON).
+	MethodName string
+	// Params is the name of the operation's parameters struct.
 	Params string
-	Body   *GenSchema
+	// MediaType of the request body, empty if there is no body.
+	MediaType string
+	// Body is the request body schema, nil if there is no body.
+	Body *GenSchema
+	// Form has the properties of a multipart/form-data or
+	// application/x-www-form-urlencoded body, nil for other media types.
+	Form []GenFormField
+}
+
+// IsMultipart reports whether the body is multipart/form-data.
+func (h GenHandler) IsMultipart() bool {
+	return strings.EqualFold(h.MediaType, "multipart/form-data")
+}
+
+// IsXML reports whether the body is XML.
+func (h GenHandler) IsXML() bool {
+	return isXMLMediaType(h.MediaType)
+}
+
+// IsURLEncoded reports whether the body is
+// application/x-www-form-urlencoded.
+func (h GenHandler) IsURLEncoded() bool {
+	return strings.EqualFold(h.MediaType, "application/x-www-form-urlencoded")
+}
+
+// GenFormField is one property of a form body.
+type GenFormField struct {
+	// Name is the Go field name.
+	Name string
+	// Property is the name of the form field.
+	Property string
+	// Required is set for properties listed in the body's required.
+	Required bool
+	// Style and Explode apply to urlencoded bodies.
+	Style   string
+	Explode bool
+	// ContentType a multipart part must have, from the encoding object.
+	// Empty if the spec doesn't restrict it.
+	ContentType string
+}
+
+// GenParameter is a single path, query, header or cookie parameter.
+type GenParameter struct {
+	// Name is the Go field name.
+	Name string
+	// ParamName is the name as it appears in the request.
+	ParamName string
+	// In is one of path, query, header or cookie.
+	In          string
+	Required    bool
+	Description string
+	Deprecated  bool
+	// Style and Explode give the serialization, e.g. form and true.
+	Style   string
+	Explode bool
+	// AllowReserved means reserved characters in query values aren't
+	// percent-encoded.
+	AllowReserved bool
+	Schema        *GenSchema
+}
+
+// GenSecurityRequirement is a set of schemes that must all be satisfied,
+// ordered by scheme name.
+type GenSecurityRequirement []GenSchemeScopes
+
+// GenSchemeScopes is a security scheme and the scopes required of it.
+type GenSchemeScopes struct {
+	Scheme string
+	Scopes []string
+}
+
+// GenSecurityScheme is an entry in components.securitySchemes.
+type GenSecurityScheme struct {
+	// Name is the scheme's key, as used by security requirements.
+	Name        string
+	Type        string // apiKey | http | oauth2 | openIdConnect
+	Description string
+	// ParamName and In locate an apiKey (header, query or cookie).
+	ParamName string
+	In        string
+	// Scheme is the lower case http auth scheme, e.g. basic or bearer.
+	Scheme string
+	// Scopes available for oauth2, sorted.
+	Scopes []string
+}
+
+// GenResponse is the typed responder for one status code and media type.
+type GenResponse struct {
+	// Name of the responder type, e.g. ListPets200Response.
+	Name string
+	// StatusCode as written in the spec: a code, a range like 2XX, or
+	// default.
+	StatusCode string
+	// HasStatusCode is set when StatusCode is a single code; otherwise the
+	// responder's constructor takes the code as an argument.
+	HasStatusCode bool
+	Description   string
+	// ContentType is empty for responses without a body.
+	ContentType string
+	// Body is nil for responses without a body, and for binary and event
+	// stream responses, whose body type is fixed.
+	Body *GenSchema
+	// IsBinary is set for application/octet-stream and `format: binary`
+	// bodies, which are streamed from an io.Reader.
+	IsBinary bool
+	// IsText is set for text/* bodies with a primitive schema, which are
+	// written as-is rather than as JSON.
+	IsText bool
+	// IsEventStream is set for text/event-stream, streamed from a channel
+	// of events.
+	IsEventStream bool
+	// IsXML is set for application/xml, text/xml and +xml media types.
+	IsXML bool
+	// XMLNamespace and XMLName name the root element of an XML body, and
+	// XMLItemName the elements of a top level array. Empty names fall back
+	// to the Go type name.
+	XMLNamespace string
+	XMLName      string
+	XMLItemName  string
+	// Headers is nil if the status code declares no headers.
+	Headers *GenResponseHeaders
+}
+
+// HasBody reports whether the responder takes a body.
+func (r GenResponse) HasBody() bool {
+	return r.Body != nil || r.IsBinary || r.IsText || r.IsEventStream
+}
+
+// GenNegotiatedResponse is a responder that writes whichever of its
+// Variants best matches the request's Accept header.
+type GenNegotiatedResponse struct {
+	// Name of the responder type, e.g. ListPets200NegotiatedResponse.
+	Name          string
+	StatusCode    string
+	HasStatusCode bool
+	Description   string
+	Body          *GenSchema
+	Headers       *GenResponseHeaders
+	// Variants are the single media type responses, in the spec's order.
+	// The first is used if the request doesn't say what it accepts.
+	Variants []GenResponse
+}
+
+// GenResponseHeaders is the typed header struct shared by every media type
+// of a status code.
+type GenResponseHeaders struct {
+	// Name of the struct, e.g. ListPets200Headers.
+	Name    string
+	Headers []GenHeader
+}
+
+// GenHeader is a single response header.
+type GenHeader struct {
+	// Name is the Go field name.
+	Name string
+	// ArgName is the constructor argument name for required headers.
+	ArgName string
+	// HeaderName is the header as sent on the wire.
+	HeaderName  string
+	Required    bool
+	Description string
+	Deprecated  bool
+	Schema      *GenSchema
+}
+
+// Required returns the headers that must be passed to the constructor.
+func (h *GenResponseHeaders) Required() []GenHeader {
+	required := []GenHeader{}
+	for _, header := range h.Headers {
+		if header.Required {
+			required = append(required, header)
+		}
+	}
+	return required
 }
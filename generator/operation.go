@@ -2,6 +2,7 @@ package generator
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/mllrjb/hackathon-go-openapi-v3/parser"
@@ -9,6 +10,13 @@ import (
 )
 
 func MediaTypeToTitle(mediaType string) string {
+	switch strings.ToLower(mediaType) {
+	case "multipart/form-data":
+		return "Multipart"
+	case "application/x-www-form-urlencoded":
+		return "Form"
+	}
+
 	// TODO: handle */* and non application/json
 	mediaType = strings.TrimPrefix(mediaType, "application/")
 	mediaType = strings.TrimSuffix(mediaType, "json")
@@ -17,32 +25,68 @@ func MediaTypeToTitle(mediaType string) string {
 
 func GenerateOperation(op *parser.Operation) GenOperation {
 	gOp := GenOperation{
-		Name:   op.Name,
-		Path:   op.Path,
-		Method: op.Method,
+		OperationID: op.OperationID,
+		Name:        op.Name,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Deprecated:  op.Deprecated,
+		Tags:        op.Tags,
+		Path:        op.Path,
+		Method:      op.Method,
+	}
+
+	for _, requirement := range op.Security {
+		gOp.Security = append(gOp.Security, generateSecurityRequirement(requirement))
+	}
+
+	for _, p := range op.Parameters {
+		fieldName := utils.ToPascalCase(p.Name)
+		gs := GenerateSchema(p.Schema, fmt.Sprintf("%s%s", op.Name, fieldName), "operation")
+		if gs.IsObject {
+			gOp.Models = append(gOp.Models, &gs)
+		}
+		gOp.Models = append(gOp.Models, GetAllNestedModels(&gs)...)
+		gOp.Parameters = append(gOp.Parameters, GenParameter{
+			Name:          fieldName,
+			ParamName:     p.Name,
+			In:            p.In,
+			Required:      p.Required,
+			Description:   p.Description,
+			Deprecated:    p.Deprecated,
+			Style:         p.Style,
+			Explode:       p.Explode,
+			AllowReserved: p.AllowReserved,
+			Schema:        &gs,
+		})
 	}
+
 	paramsName := fmt.Sprintf("%sParameters", op.Name)
-	handlerBase := fmt.Sprintf("%sHandler", op.Name)
 
 	if len(op.Requests) == 0 {
 		// generic handler for no request body
 		gOp.Handlers = []GenHandler{
 			GenHandler{
-				Name:   handlerBase,
-				Params: paramsName,
+				MethodName: op.Name,
+				Params:     paramsName,
 			},
 		}
 	} else {
 		for _, r := range op.Requests {
 			var handlerBodyName string
 			mediaTypeTitle := MediaTypeToTitle(r.Accept)
-			handlerName := fmt.Sprintf("%s_%s", handlerBase, mediaTypeTitle)
+			methodName := op.Name
+			if len(mediaTypeTitle) > 0 {
+				methodName = fmt.Sprintf("%s_%s", op.Name, mediaTypeTitle)
+			}
 			if r.IsComponent() {
 				// TODO:??
 			} else {
 				handlerBodyName = fmt.Sprintf("%s%s", op.Name, mediaTypeTitle)
 
 				gs := GenerateSchema(r.Body, handlerBodyName, "operation")
+				if strings.EqualFold(r.Accept, "multipart/form-data") {
+					setFileParts(&gs)
+				}
 				nested := GetAllNestedModels(&gs)
 
 				// ignore top level slices, since we just use their type directly
@@ -56,17 +100,310 @@ func GenerateOperation(op *parser.Operation) GenOperation {
 					fmt.Printf("model %s.%s (%s)\n", m.Pkg, m.ReferenceType, m.GoType)
 				}
 				gOp.Handlers = append(gOp.Handlers, GenHandler{
-					Name:   handlerName,
-					Params: paramsName,
-					Body:   &gs,
+					MethodName: methodName,
+					Params:     paramsName,
+					MediaType:  r.Accept,
+					Body:       &gs,
+					Form:       generateFormFields(r),
 				})
 			}
 		}
 	}
 
+	headersByStatus := map[string]*GenResponseHeaders{}
+	for _, r := range op.Responses {
+		statusName := utils.ToPascalCase(r.StatusCode)
+		mediaTypeTitle := MediaTypeToTitle(r.ContentType)
+
+		gr := GenResponse{
+			Name:          fmt.Sprintf("%s%sResponse", op.Name, statusName),
+			StatusCode:    r.StatusCode,
+			HasStatusCode: isStatusCode(r.StatusCode),
+			Description:   r.Description,
+			ContentType:   r.ContentType,
+		}
+		if len(mediaTypeTitle) > 0 {
+			gr.Name = fmt.Sprintf("%s_%s", gr.Name, mediaTypeTitle)
+		}
+
+		mediaType := strings.ToLower(strings.TrimSpace(strings.Split(r.ContentType, ";")[0]))
+		switch {
+		case mediaType == "text/event-stream":
+			gr.IsEventStream = true
+		case mediaType == "application/octet-stream" || isBinarySchema(r.Body):
+			gr.IsBinary = true
+		case isXMLMediaType(mediaType):
+			gr.IsXML = true
+			gr.XMLNamespace, gr.XMLName, gr.XMLItemName = xmlRoot(r.Body)
+		case strings.HasPrefix(mediaType, "text/") && (r.Body == nil || r.Body.IsPrimitive()):
+			gr.IsText = true
+		}
+
+		if r.Body != nil && !gr.IsBinary && !gr.IsEventStream {
+			gs := GenerateSchema(r.Body, fmt.Sprintf("%s%s%sBody", op.Name, statusName, mediaTypeTitle), "operation")
+			if gs.IsObject {
+				gOp.Models = append(gOp.Models, &gs)
+			}
+			gOp.Models = append(gOp.Models, GetAllNestedModels(&gs)...)
+			gr.Body = &gs
+		}
+
+		if len(r.Headers) > 0 {
+			headers, ok := headersByStatus[r.StatusCode]
+			if !ok {
+				headers = generateResponseHeaders(fmt.Sprintf("%s%sHeaders", op.Name, statusName), r.Headers)
+				headersByStatus[r.StatusCode] = headers
+				gOp.ResponseHeaders = append(gOp.ResponseHeaders, *headers)
+			}
+			gr.Headers = headers
+		}
+
+		gOp.Responses = append(gOp.Responses, gr)
+	}
+
+	gOp.NegotiatedResponses = negotiatedResponses(op.Name, gOp.Responses)
+
 	return gOp
 }
 
+// negotiatedResponses groups responses that share a status code and body
+// type but differ in media type, e.g. JSON and XML.
+func negotiatedResponses(opName string, responses []GenResponse) []GenNegotiatedResponse {
+	negotiated := []GenNegotiatedResponse{}
+	byStatus := map[string][]GenResponse{}
+	statuses := []string{}
+	for _, r := range responses {
+		if _, ok := byStatus[r.StatusCode]; !ok {
+			statuses = append(statuses, r.StatusCode)
+		}
+		byStatus[r.StatusCode] = append(byStatus[r.StatusCode], r)
+	}
+
+	for _, status := range statuses {
+		variants := byStatus[status]
+		if len(variants) < 2 {
+			continue
+		}
+
+		same := true
+		for _, v := range variants {
+			same = same && v.Body != nil && !v.IsBinary && !v.IsEventStream &&
+				ref(v.Body, "operation") == ref(variants[0].Body, "operation")
+		}
+		if !same {
+			continue
+		}
+
+		first := variants[0]
+		negotiated = append(negotiated, GenNegotiatedResponse{
+			Name:          fmt.Sprintf("%s%sNegotiatedResponse", opName, utils.ToPascalCase(status)),
+			StatusCode:    status,
+			HasStatusCode: first.HasStatusCode,
+			Description:   first.Description,
+			Body:          first.Body,
+			Headers:       first.Headers,
+			Variants:      variants,
+		})
+	}
+	return negotiated
+}
+
+func isXMLMediaType(mediaType string) bool {
+	mediaType = strings.ToLower(strings.TrimSpace(strings.Split(mediaType, ";")[0]))
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// generateFormFields lists the properties of a form body with their
+// encoding, ordered by property name.
+func generateFormFields(r parser.Request) []GenFormField {
+	if !r.IsForm() {
+		return nil
+	}
+	body, ok := r.Body.(*parser.StructSchemaModel)
+	if !ok {
+		return nil
+	}
+
+	names := []string{}
+	for name := range body.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := []GenFormField{}
+	for _, name := range names {
+		field := GenFormField{
+			Name:     utils.ToPascalCase(name),
+			Property: name,
+			Style:    "form",
+			Explode:  true,
+		}
+		for _, required := range body.Required {
+			field.Required = field.Required || required == name
+		}
+		for _, e := range r.Encoding {
+			if e.Property == name {
+				field.Style = e.Style
+				field.Explode = e.Explode
+				field.ContentType = e.ContentType
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func isBinarySchema(m parser.SchemaModel) bool {
+	p, ok := m.(*parser.PrimitiveSchemaModel)
+	return ok && p.GetType() == "string" && p.Format == "binary"
+}
+
+// isStatusCode reports whether status is a single HTTP status code, rather
+// than a range (2XX) or default.
+func isStatusCode(status string) bool {
+	if len(status) != 3 {
+		return false
+	}
+	for _, r := range status {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func generateResponseHeaders(name string, headers []parser.Header) *GenResponseHeaders {
+	gh := GenResponseHeaders{
+		Name: name,
+	}
+	for _, h := range headers {
+		fieldName := utils.ToPascalCase(h.Name)
+		gs := GenerateSchema(h.Schema, fieldName, "operation")
+		gh.Headers = append(gh.Headers, GenHeader{
+			Name:        fieldName,
+			ArgName:     utils.ToCamelCase(h.Name),
+			HeaderName:  h.Name,
+			Required:    h.Required,
+			Description: h.Description,
+			Deprecated:  h.Deprecated,
+			Schema:      &gs,
+		})
+	}
+	return &gh
+}
+
+func generateSecurityRequirement(requirement parser.SecurityRequirement) GenSecurityRequirement {
+	schemes := []string{}
+	for scheme := range requirement {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	gr := GenSecurityRequirement{}
+	for _, scheme := range schemes {
+		gr = append(gr, GenSchemeScopes{
+			Scheme: scheme,
+			Scopes: requirement[scheme],
+		})
+	}
+	return gr
+}
+
+func GenerateSecurityScheme(s *parser.SecurityScheme) GenSecurityScheme {
+	gs := GenSecurityScheme{
+		Name:        s.Name,
+		Type:        s.Type,
+		Description: s.Description,
+		ParamName:   s.ParamName,
+		In:          s.In,
+		Scheme:      s.Scheme,
+	}
+	for scope := range s.Scopes {
+		gs.Scopes = append(gs.Scopes, scope)
+	}
+	sort.Strings(gs.Scopes)
+	return gs
+}
+
+// operationImports returns the import paths of the generated packages that
+// genOp's types refer to.
+func operationImports(genOp *GenOperation, packagePath string) []string {
+	pkgs := map[string]bool{}
+	for _, p := range genOp.Parameters {
+		referencedPackages(p.Schema, "operation", pkgs)
+	}
+	for _, m := range genOp.Models {
+		referencedPackages(m, "operation", pkgs)
+	}
+	for _, r := range genOp.Responses {
+		referencedPackages(r.Body, "operation", pkgs)
+	}
+	for _, h := range genOp.ResponseHeaders {
+		for _, header := range h.Headers {
+			referencedPackages(header.Schema, "operation", pkgs)
+		}
+	}
+
+	imports := []string{}
+	for pkg := range pkgs {
+		imports = append(imports, fmt.Sprintf("%s/%s", packagePath, pkg))
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// routingImports returns the import paths that the request bodies of ops
+// need in the root generated package.
+func routingImports(ops []*GenOperation, packagePath string) []string {
+	pkgs := map[string]bool{"operation": true}
+	for _, op := range ops {
+		for _, h := range op.Handlers {
+			referencedPackages(h.Body, "generated", pkgs)
+		}
+	}
+
+	imports := []string{}
+	for pkg := range pkgs {
+		imports = append(imports, fmt.Sprintf("%s/%s", packagePath, pkg))
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+const defaultTag = "Default"
+
+// serverGroups splits ops into the interfaces that make up ServerInterface.
+// Without splitByTag there is a single, unnamed group; otherwise there is one
+// group per operation's first tag.
+func serverGroups(ops []*GenOperation, splitByTag bool) []*GenServerGroup {
+	if !splitByTag {
+		return []*GenServerGroup{
+			&GenServerGroup{Operations: ops},
+		}
+	}
+
+	groups := []*GenServerGroup{}
+	byTag := map[string]*GenServerGroup{}
+	for _, op := range ops {
+		tag := defaultTag
+		if len(op.Tags) > 0 {
+			tag = op.Tags[0]
+		}
+
+		group, ok := byTag[tag]
+		if !ok {
+			group = &GenServerGroup{
+				Name: fmt.Sprintf("%sServer", utils.ToPascalCase(tag)),
+				Tag:  tag,
+			}
+			byTag[tag] = group
+			groups = append(groups, group)
+		}
+		group.Operations = append(group.Operations, op)
+	}
+	return groups
+}
+
 // operation:
 
 // for each request
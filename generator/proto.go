@@ -0,0 +1,1071 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/parser"
+	"github.com/mllrjb/hackathon-go-openapi-v3/utils"
+)
+
+// protoFiles are the templates of the gRPC backend, executed once per spec
+// with a ProtoData.
+var protoFiles = []supportFile{
+	{Template: "proto.tmpl", Output: "proto/api.proto"},
+	{Template: "protoconv.go.tmpl", Output: "protoconv/convert.go"},
+}
+
+// protoLockFile keeps the field numbers assigned so far, next to the .proto
+// file. It must be kept with the spec, since numbers that change break
+// every existing client.
+const protoLockFile = "proto/api.lock.json"
+
+// ProtoData is what proto.tmpl and protoconv.go.tmpl are executed with.
+type ProtoData struct {
+	// Package is the proto package, from the title of the spec.
+	Package string
+	// GoPackage is the import path of the code protoc-gen-go generates from
+	// the .proto file, next to it in the output directory.
+	GoPackage string
+	// PackagePath is the import path of the generated root package.
+	PackagePath string
+	// Service is the name of the service with an rpc per handler.
+	Service string
+	// Imports are the .proto files imported, sorted.
+	Imports  []string
+	Enums    []*ProtoEnum
+	Messages []*ProtoMessage
+	RPCs     []*ProtoRPC
+}
+
+// HasConversions reports whether protoconv.go.tmpl has anything to convert.
+func (d ProtoData) HasConversions() bool {
+	for _, m := range d.Messages {
+		if m.Component != nil {
+			return true
+		}
+	}
+	return len(d.Enums) > 0
+}
+
+// ProtoMessage is a message, top level or nested.
+type ProtoMessage struct {
+	Name string
+	// GoName is the name protoc-gen-go gives the message's Go type.
+	GoName      string
+	Description string
+	Deprecated  bool
+	// Fields are ordered by number.
+	Fields []*ProtoField
+	// Oneof holds the variants of a oneOf or anyOf schema, nil if it has
+	// none.
+	Oneof *ProtoOneof
+	// Reserved are the numbers and names of fields in the lock file that
+	// the schema no longer has.
+	Reserved      []int
+	ReservedNames []string
+	Messages      []*ProtoMessage
+	// Component is the schema of the component the message is generated
+	// from, nil for nested, request and response messages. Only these get
+	// conversion functions.
+	Component *GenSchema
+
+	// path is the message's key in the lock file, e.g. Pet.Owner.
+	path string
+}
+
+// ProtoOneof is the oneof of a message.
+type ProtoOneof struct {
+	Name   string
+	Fields []*ProtoField
+}
+
+// ProtoField is a field of a message.
+type ProtoField struct {
+	// Name is the snake_case field name.
+	Name   string
+	Number int
+	// Type is as written in the .proto file, e.g. int64 or Pet.
+	Type     string
+	Repeated bool
+	// Comment is written after the field, e.g. for header parameters.
+	Comment     string
+	Description string
+	Deprecated  bool
+
+	// schema is the field's schema, and goName the name of the component
+	// struct field it converts to and from.
+	schema *GenSchema
+	goName string
+	// message is set for a field of a nested message type, item for a
+	// repeated field whose items need a nested wrapper message.
+	message *ProtoMessage
+	item    *ProtoField
+	// component is set for a field of a component that has conversion
+	// functions, and cast for one of a primitive component, which is
+	// converted to its scalar with a cast.
+	component string
+	cast      bool
+}
+
+// ProtoEnum is a string enum component.
+type ProtoEnum struct {
+	Name string
+	// GoName is the name protoc-gen-go gives the enum's Go type. Its values
+	// are constants named <GoName>_<value name>.
+	GoName      string
+	Description string
+	Deprecated  bool
+	// Unspecified is the name of the zero value, <NAME>_UNSPECIFIED.
+	Unspecified string
+	Values      []ProtoEnumValue
+	Reserved    []int
+}
+
+// ProtoEnumValue is a value of an enum, apart from the zero value.
+type ProtoEnumValue struct {
+	Name   string
+	Number int
+	// Value is the string value in the spec.
+	Value string
+}
+
+// ProtoRPC is the rpc of a handler.
+type ProtoRPC struct {
+	Name                 string
+	Summary, Description string
+	Deprecated           bool
+	Request, Response    string
+	// ServerStreaming is set for text/event-stream responses.
+	ServerStreaming bool
+	// HTTP is the google.api.http rule, nil for all but the first handler
+	// of an operation, since a rule can only be bound once.
+	HTTP *ProtoHTTPRule
+	// BoundBy is the rpc that has the rule, if HTTP is nil.
+	BoundBy string
+}
+
+// ProtoHTTPRule is a google.api.http annotation.
+type ProtoHTTPRule struct {
+	// Method is get, put, post, delete or patch, or custom for any other.
+	Method string
+	// Kind is the HTTP method of a custom rule.
+	Kind string
+	// Path has the names of the request's fields in its variables.
+	Path         string
+	Body         string
+	ResponseBody string
+}
+
+// protoLock is the field numbers assigned so far, by message and field
+// name, and the numbers of enum values. Numbers are never reused: those of
+// fields that are gone are reserved.
+type protoLock struct {
+	Messages map[string]map[string]int `json:"messages"`
+	Enums    map[string]map[string]int `json:"enums"`
+}
+
+func readProtoLock(filepath string) (*protoLock, error) {
+	lock := &protoLock{Messages: map[string]map[string]int{}, Enums: map[string]map[string]int{}}
+	bytes, err := ioutil.ReadFile(filepath)
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %v", filepath, err)
+	}
+	if err := json.Unmarshal(bytes, lock); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", filepath, err)
+	}
+	if lock.Messages == nil {
+		lock.Messages = map[string]map[string]int{}
+	}
+	if lock.Enums == nil {
+		lock.Enums = map[string]map[string]int{}
+	}
+	return lock, nil
+}
+
+// number returns the number of a field or enum value, assigning the next
+// one after every number ever used in scope if it has none.
+func number(numbers map[string]map[string]int, scope string, name string, first int) int {
+	if numbers[scope] == nil {
+		numbers[scope] = map[string]int{}
+	}
+	if n, ok := numbers[scope][name]; ok {
+		return n
+	}
+	n := first
+	for _, used := range numbers[scope] {
+		if used >= n {
+			n = used + 1
+		}
+	}
+	numbers[scope][name] = n
+	return n
+}
+
+// reserved returns the numbers and names in scope that aren't current.
+func reserved(numbers map[string]int, current map[string]bool) ([]int, []string) {
+	var ns []int
+	var names []string
+	for name, n := range numbers {
+		if !current[name] {
+			ns = append(ns, n)
+			names = append(names, name)
+		}
+	}
+	sort.Ints(ns)
+	sort.Strings(names)
+	return ns, names
+}
+
+// GenerateProtoFiles writes a .proto file with a message per component and
+// an rpc per handler to config.OutputDir, and Go functions converting the
+// component structs to and from the messages. Field numbers are read from
+// and saved to the lock file there.
+func GenerateProtoFiles(walker parser.Walker, config Config) {
+	t, err := LoadTemplates(config.TemplateDir, templateFuncs())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	lockPath := fmt.Sprintf("%s/%s", config.OutputDir, protoLockFile)
+	lock, err := readProtoLock(lockPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	data := protoData(walker, templateData(walker, config), lock)
+	for _, f := range protoFiles {
+		outPath := fmt.Sprintf("%s/%s", config.OutputDir, f.Output)
+		if src, ok := t.Verbatim[f.Template]; ok {
+			if err := copyFile(src, outPath); err != nil {
+				fmt.Printf("error copying .go file: %v\n", err)
+				os.Exit(1)
+			}
+			continue
+		}
+
+		tmpl := t.Lookup(f.Template)
+		if tmpl == nil {
+			fmt.Printf("could not find %s template\n", f.Template)
+			os.Exit(1)
+		}
+
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, data)
+		if err != nil {
+			fmt.Printf("error processing %s: %v\n", f.Template, err)
+			os.Exit(1)
+		}
+
+		err = writeFile(outPath, buf.Bytes())
+		if err != nil {
+			fmt.Printf("unable to write %s: %v\n", f.Output, err)
+			os.Exit(1)
+		}
+	}
+
+	lockBytes, err := json.MarshalIndent(lock, "", "  ")
+	if err == nil {
+		err = writeFile(lockPath, append(lockBytes, '\n'))
+	}
+	if err != nil {
+		fmt.Printf("unable to write %s: %v\n", protoLockFile, err)
+		os.Exit(1)
+	}
+}
+
+type protoBuilder struct {
+	lock *protoLock
+	// components are the schemas of TemplateData by name.
+	components map[string]*GenSchema
+	// names are the top level message and enum names taken so far.
+	names   map[string]bool
+	imports map[string]bool
+}
+
+func protoData(walker parser.Walker, data TemplateData, lock *protoLock) ProtoData {
+	b := &protoBuilder{
+		lock:       lock,
+		components: map[string]*GenSchema{},
+		names:      map[string]bool{},
+		imports:    map[string]bool{},
+	}
+	for _, gs := range data.Schemas {
+		b.components[gs.ReceiverName] = gs
+		b.names[gs.ReceiverName] = true
+	}
+
+	pkg := protoSnakeCase(walker.GetInfo().Title)
+	if len(pkg) == 0 {
+		pkg = "api"
+	}
+	pd := ProtoData{
+		Package:     pkg,
+		GoPackage:   data.PackagePath + "/proto",
+		PackagePath: data.PackagePath,
+		Service:     utils.ToPascalCase(pkg) + "Service",
+	}
+
+	for _, gs := range data.Schemas {
+		switch {
+		case isProtoEnum(gs):
+			pd.Enums = append(pd.Enums, b.enum(gs))
+		case gs.IsObject || gs.IsSlice || len(gs.Variants) > 0:
+			m := b.message(gs.ReceiverName, gs.ReceiverName, gs.ReceiverName, gs)
+			m.Description = gs.Description
+			m.Deprecated = gs.Deprecated
+			if gs.IsObject || gs.IsSlice {
+				m.Component = gs
+			}
+			pd.Messages = append(pd.Messages, m)
+		}
+	}
+
+	for _, op := range data.Operations {
+		pd.RPCs = append(pd.RPCs, b.rpcs(op, &pd)...)
+	}
+
+	for imp := range b.imports {
+		pd.Imports = append(pd.Imports, imp)
+	}
+	sort.Strings(pd.Imports)
+	return pd
+}
+
+// isProtoEnum reports whether a component becomes a proto enum: only string
+// enums do, since the values of others can't be names.
+func isProtoEnum(gs *GenSchema) bool {
+	return gs.IsPrimitive && gs.Type == "string" && len(gs.Enum) > 0 && len(gs.Variants) == 0
+}
+
+func (b *protoBuilder) enum(gs *GenSchema) *ProtoEnum {
+	e := &ProtoEnum{Name: gs.ReceiverName, GoName: protoGoName(gs.ReceiverName), Description: gs.Description, Deprecated: gs.Deprecated}
+	prefix := strings.ToUpper(protoSnakeCase(gs.ReceiverName)) + "_"
+	e.Unspecified = prefix + "UNSPECIFIED"
+	current := map[string]bool{}
+	for _, v := range gs.Enum {
+		value := fmt.Sprint(v)
+		name := prefix + strings.ToUpper(protoSnakeCase(value))
+		if len(protoSnakeCase(value)) == 0 || current[name] || name == e.Unspecified {
+			name = fmt.Sprintf("%sVALUE_%d", prefix, len(e.Values)+1)
+		}
+		current[name] = true
+		e.Values = append(e.Values, ProtoEnumValue{Name: name, Number: number(b.lock.Enums, e.Name, name, 1), Value: value})
+	}
+	sort.Slice(e.Values, func(i, j int) bool {
+		return e.Values[i].Number < e.Values[j].Number
+	})
+	e.Reserved, _ = reserved(b.lock.Enums[e.Name], current)
+	return e
+}
+
+// message builds a message from the properties and variants of gs, or a
+// wrapper with a repeated items field if gs is an array. path is its key
+// in the lock file.
+func (b *protoBuilder) message(name string, goName string, path string, gs *GenSchema) *ProtoMessage {
+	m := &ProtoMessage{Name: name, GoName: protoGoName(goName), path: path}
+
+	if gs.IsSlice {
+		f := &ProtoField{Name: "items", schema: gs}
+		b.setInlineType(f, gs, m)
+		b.addFields(m, []*ProtoField{f}, nil)
+		return m
+	}
+
+	var fields []*ProtoField
+	taken := map[string]bool{}
+	for _, p := range gs.SortedProperties() {
+		f := &ProtoField{
+			Name:        uniqueName(protoSnakeCase(p.ReceiverName), taken),
+			Description: p.Description,
+			Deprecated:  p.Deprecated,
+			schema:      p,
+			goName:      utils.ToPascalCase(p.ReceiverName),
+		}
+		b.setType(f, p, m)
+		fields = append(fields, f)
+	}
+
+	var variants []*ProtoField
+	for i, v := range gs.Variants {
+		name := fmt.Sprintf("variant_%d", i+1)
+		if v.IsDefinedElsewhere {
+			name = protoSnakeCase(v.ReferenceType)
+		}
+		f := &ProtoField{Name: uniqueName(name, taken), Description: v.Description, schema: v}
+		b.setType(f, v, m)
+		if f.Repeated {
+			// a oneof can't have repeated fields
+			f.Type = b.wrapper(m, protoGoName(f.Name), f)
+			f.Repeated = false
+		}
+		variants = append(variants, f)
+	}
+	b.addFields(m, fields, variants)
+	return m
+}
+
+// addFields numbers the fields and variants of m, and reserves the numbers
+// of fields it used to have.
+func (b *protoBuilder) addFields(m *ProtoMessage, fields []*ProtoField, variants []*ProtoField) {
+	current := map[string]bool{}
+	for _, f := range append(append([]*ProtoField{}, fields...), variants...) {
+		f.Number = number(b.lock.Messages, m.path, f.Name, 1)
+		current[f.Name] = true
+	}
+	byNumber := func(fs []*ProtoField) {
+		sort.Slice(fs, func(i, j int) bool {
+			return fs[i].Number < fs[j].Number
+		})
+	}
+
+	byNumber(fields)
+	m.Fields = fields
+	if len(variants) > 0 {
+		byNumber(variants)
+		m.Oneof = &ProtoOneof{Name: "variant", Fields: variants}
+	}
+	m.Reserved, m.ReservedNames = reserved(b.lock.Messages[m.path], current)
+}
+
+// setType sets the type of a field with schema gs, adding any message it
+// needs nested in parent.
+func (b *protoBuilder) setType(f *ProtoField, gs *GenSchema, parent *ProtoMessage) {
+	switch {
+	case gs == nil:
+		f.Type = b.value()
+	case gs.IsDefinedElsewhere || b.isComponent(gs):
+		c := b.components[gs.ReferenceType]
+		if c != nil && c.IsPrimitive && !isProtoEnum(c) && len(c.Variants) == 0 {
+			if f.Type = protoScalar(c); len(f.Type) == 0 {
+				f.Type = b.value()
+				return
+			}
+			f.component = gs.ReferenceType
+			f.cast = true
+			return
+		}
+		f.Type = gs.ReferenceType
+		if c != nil && (c.IsObject || c.IsSlice || isProtoEnum(c)) {
+			f.component = gs.ReferenceType
+		}
+	default:
+		b.setInlineType(f, gs, parent)
+	}
+}
+
+// setInlineType is setType for a schema that isn't a component.
+func (b *protoBuilder) setInlineType(f *ProtoField, gs *GenSchema, parent *ProtoMessage) {
+	switch {
+	case gs.IsSlice:
+		item := &ProtoField{Name: f.Name + "_item", schema: gs.Items}
+		b.setType(item, gs.Items, parent)
+		if item.Repeated {
+			// a repeated field can't be repeated
+			item.Type = b.wrapper(parent, protoGoName(item.Name), item)
+			item.Repeated = false
+		}
+		f.Type = item.Type
+		f.Repeated = true
+		f.item = item
+	case gs.IsObject || len(gs.Variants) > 0:
+		name := b.nestedName(parent, protoGoName(f.Name))
+		f.message = b.message(name, parent.GoName+"_"+name, parent.path+"."+name, gs)
+		f.message.Description = gs.Description
+		parent.Messages = append(parent.Messages, f.message)
+		f.Type = name
+	case gs.IsPrimitive && len(protoScalar(gs)) > 0:
+		f.Type = protoScalar(gs)
+	default:
+		f.Type = b.value()
+	}
+}
+
+// isComponent reports whether gs is itself a top level schema, such as the
+// inline object items of an array component.
+func (b *protoBuilder) isComponent(gs *GenSchema) bool {
+	return len(gs.ReferenceType) > 0 && b.components[gs.ReferenceType] == gs
+}
+
+// isMessage reports whether gs refers to a component that is a message.
+func (b *protoBuilder) isMessage(gs *GenSchema) bool {
+	c := b.components[gs.ReferenceType]
+	return gs.IsDefinedElsewhere && c != nil && (!c.IsPrimitive || len(c.Variants) > 0)
+}
+
+func (b *protoBuilder) value() string {
+	b.imports["google/protobuf/struct.proto"] = true
+	return "google.protobuf.Value"
+}
+
+// wrapper nests a message with the repeated field item in parent, for
+// where a repeated field isn't allowed.
+func (b *protoBuilder) wrapper(parent *ProtoMessage, name string, item *ProtoField) string {
+	name = b.nestedName(parent, name)
+	w := &ProtoMessage{Name: name, GoName: parent.GoName + "_" + protoGoName(name), path: parent.path + "." + name}
+	items := &ProtoField{Name: "items", Type: item.Type, Repeated: true, schema: item.schema, message: item.message, item: item.item, component: item.component, cast: item.cast}
+	b.addFields(w, []*ProtoField{items}, nil)
+	parent.Messages = append(parent.Messages, w)
+	item.message = w
+	return name
+}
+
+// nestedName is name, unless that is taken by a nested message of parent
+// or a top level one, which it would hide.
+func (b *protoBuilder) nestedName(parent *ProtoMessage, name string) string {
+	taken := func(n string) bool {
+		if b.names[n] {
+			return true
+		}
+		for _, m := range parent.Messages {
+			if m.Name == n {
+				return true
+			}
+		}
+		return false
+	}
+	candidate := name
+	if taken(candidate) {
+		candidate = name + "Object"
+	}
+	for i := 2; taken(candidate); i++ {
+		candidate = fmt.Sprintf("%sObject%d", name, i)
+	}
+	return candidate
+}
+
+// topLevelName is name, or name with a suffix if a component already has it.
+func (b *protoBuilder) topLevelName(name string) string {
+	candidate := name
+	for i := 2; b.names[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	b.names[candidate] = true
+	return candidate
+}
+
+// rpcs returns an rpc per handler of op. Each has its own request message,
+// with a field per parameter and a body field if there is a request body.
+// They share a response: the first successful one with content, preferring
+// JSON.
+func (b *protoBuilder) rpcs(op *GenOperation, pd *ProtoData) []*ProtoRPC {
+	response, responseBody, streaming := b.response(op, pd)
+
+	var rpcs []*ProtoRPC
+	for i, h := range op.Handlers {
+		rpc := &ProtoRPC{
+			Name:            strings.ReplaceAll(h.MethodName, "_", ""),
+			Summary:         op.Summary,
+			Description:     op.Description,
+			Deprecated:      op.Deprecated,
+			Response:        response,
+			ServerStreaming: streaming,
+		}
+		rpc.Request = b.topLevelName(rpc.Name + "Request")
+
+		request := &ProtoMessage{Name: rpc.Request, GoName: protoGoName(rpc.Request), path: rpc.Request}
+		var fields []*ProtoField
+		taken := map[string]bool{}
+		paramFields := map[string]string{}
+		for _, p := range op.Parameters {
+			f := &ProtoField{
+				Name:        uniqueName(protoSnakeCase(p.ParamName), taken),
+				Description: p.Description,
+				Deprecated:  p.Deprecated,
+				schema:      p.Schema,
+			}
+			if p.In == "header" || p.In == "cookie" {
+				f.Comment = p.In + " " + p.ParamName
+			}
+			paramFields[p.ParamName] = f.Name
+			b.setType(f, p.Schema, request)
+			fields = append(fields, f)
+		}
+		body := ""
+		if h.Body != nil {
+			f := &ProtoField{Name: uniqueName("body", taken), Comment: h.MediaType, schema: h.Body}
+			b.setType(f, h.Body, request)
+			body = f.Name
+			fields = append(fields, f)
+		} else if len(h.MediaType) > 0 {
+			b.imports["google/api/httpbody.proto"] = true
+			fields = append(fields, &ProtoField{Name: uniqueName("body", taken), Type: "google.api.HttpBody", Comment: h.MediaType})
+			body = "body"
+		}
+		b.addFields(request, fields, nil)
+		pd.Messages = append(pd.Messages, request)
+
+		if i == 0 {
+			rpc.HTTP = httpRule(op, paramFields, body, responseBody)
+			b.imports["google/api/annotations.proto"] = true
+		} else {
+			rpc.BoundBy = rpcs[0].Name
+		}
+		rpcs = append(rpcs, rpc)
+	}
+	return rpcs
+}
+
+// response returns the message an operation's rpcs return, and the field
+// of it that is the HTTP response body, if it isn't the whole message.
+func (b *protoBuilder) response(op *GenOperation, pd *ProtoData) (string, string, bool) {
+	var chosen *GenResponse
+	for i, r := range op.Responses {
+		if !strings.HasPrefix(r.StatusCode, "2") || len(r.ContentType) == 0 {
+			continue
+		}
+		if chosen == nil || (!isJSONMediaType(chosen.ContentType) && isJSONMediaType(r.ContentType)) {
+			chosen = &op.Responses[i]
+		}
+	}
+
+	switch {
+	case chosen == nil:
+		b.imports["google/protobuf/empty.proto"] = true
+		return "google.protobuf.Empty", "", false
+	case chosen.IsBinary || chosen.IsEventStream || chosen.Body == nil:
+		b.imports["google/api/httpbody.proto"] = true
+		return "google.api.HttpBody", "", chosen.IsEventStream
+	case b.isMessage(chosen.Body):
+		return chosen.Body.ReferenceType, "", false
+	}
+
+	name := b.topLevelName(op.Name + "Response")
+	m := &ProtoMessage{Name: name, GoName: protoGoName(name), path: name}
+	f := &ProtoField{Name: "body", schema: chosen.Body}
+	b.setType(f, chosen.Body, m)
+	b.addFields(m, []*ProtoField{f}, nil)
+	pd.Messages = append(pd.Messages, m)
+	return name, "body", false
+}
+
+func isJSONMediaType(mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// httpRule binds an rpc to the operation's method and path, with its
+// variables renamed to the request fields of the path parameters.
+func httpRule(op *GenOperation, paramFields map[string]string, body string, responseBody string) *ProtoHTTPRule {
+	path := op.Path
+	for param, field := range paramFields {
+		path = strings.ReplaceAll(path, "{"+param+"}", "{"+field+"}")
+	}
+
+	rule := &ProtoHTTPRule{Method: strings.ToLower(op.Method), Path: path, Body: body, ResponseBody: responseBody}
+	switch rule.Method {
+	case "get", "put", "post", "delete", "patch":
+	default:
+		rule.Kind = op.Method
+		rule.Method = "custom"
+	}
+	return rule
+}
+
+func uniqueName(name string, taken map[string]bool) string {
+	if len(name) == 0 {
+		name = "field"
+	}
+	candidate := name
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+// protoScalar is the scalar type of a primitive schema, empty if it has no
+// type.
+func protoScalar(gs *GenSchema) string {
+	switch gs.Type {
+	case "string":
+		if gs.Format == "binary" || gs.Format == "byte" {
+			return "bytes"
+		}
+		return "string"
+	case "integer":
+		if gs.Format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if gs.Format == "float" {
+			return "float"
+		}
+		return "double"
+	case "boolean":
+		return "bool"
+	}
+	return ""
+}
+
+// protoSnakeCase is the lower snake_case of a name in any case, e.g.
+// shape_type for shapeType, ShapeType or shape-type.
+func protoSnakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])
+			if (prevLower || nextLower) && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case r < unicode.MaxASCII && (unicode.IsLower(r) || unicode.IsDigit(r)):
+			if b.Len() == 0 && unicode.IsDigit(r) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteByte('_')
+			}
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+// protoGoName is the name protoc-gen-go gives to a proto identifier in Go,
+// e.g. ShapeType for shape_type.
+func protoGoName(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isASCIILower(s[i+1]):
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isASCIILower(s[i+1]):
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isASCIILower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func isASCIILower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+// protoString quotes s as a .proto string literal.
+func protoString(s string) string {
+	return strconv.Quote(s)
+}
+
+// protoComment renders each non-empty paragraph as // comments, indented
+// by indent.
+func protoComment(indent string, paragraphs ...string) string {
+	var parts []string
+	for _, p := range paragraphs {
+		p = strings.TrimSpace(p)
+		if len(p) > 0 {
+			parts = append(parts, utils.WrapText(p, docWidth))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, line := range strings.Split(strings.Join(parts, "\n\n"), "\n") {
+		b.WriteString(strings.TrimRight(indent+"// "+line, " ") + "\n")
+	}
+	return b.String()
+}
+
+// protoMessage renders a message and the messages nested in it, indented by
+// indent.
+func protoMessage(m *ProtoMessage, indent string) string {
+	var b strings.Builder
+	inner := indent + "  "
+	b.WriteString(protoComment(indent, m.Description))
+	fmt.Fprintf(&b, "%smessage %s {\n", indent, m.Name)
+	if m.Deprecated {
+		fmt.Fprintf(&b, "%soption deprecated = true;\n", inner)
+	}
+	if len(m.Reserved) > 0 {
+		numbers := make([]string, len(m.Reserved))
+		for i, n := range m.Reserved {
+			numbers[i] = strconv.Itoa(n)
+		}
+		names := make([]string, len(m.ReservedNames))
+		for i, n := range m.ReservedNames {
+			names[i] = protoString(n)
+		}
+		fmt.Fprintf(&b, "%sreserved %s;\n", inner, strings.Join(numbers, ", "))
+		fmt.Fprintf(&b, "%sreserved %s;\n", inner, strings.Join(names, ", "))
+	}
+	for _, nested := range m.Messages {
+		b.WriteString(protoMessage(nested, inner))
+	}
+	for _, f := range m.Fields {
+		b.WriteString(protoField(f, inner))
+	}
+	if m.Oneof != nil {
+		fmt.Fprintf(&b, "%soneof %s {\n", inner, m.Oneof.Name)
+		for _, f := range m.Oneof.Fields {
+			b.WriteString(protoField(f, inner+"  "))
+		}
+		fmt.Fprintf(&b, "%s}\n", inner)
+	}
+	fmt.Fprintf(&b, "%s}\n", indent)
+	return b.String()
+}
+
+func protoField(f *ProtoField, indent string) string {
+	var b strings.Builder
+	b.WriteString(protoComment(indent, f.Description))
+	b.WriteString(indent)
+	if f.Repeated {
+		b.WriteString("repeated ")
+	}
+	fmt.Fprintf(&b, "%s %s = %d", f.Type, f.Name, f.Number)
+	if f.Deprecated {
+		b.WriteString(" [deprecated = true]")
+	}
+	b.WriteString(";")
+	if len(f.Comment) > 0 {
+		b.WriteString(" // " + strings.ReplaceAll(f.Comment, "\n", " "))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// protoToMessage is the body of a <Component>ToProto function, setting the
+// fields of m from those of v.
+func protoToMessage(m *ProtoMessage) string {
+	var b strings.Builder
+	c := &protoConverter{b: &b}
+	if m.Component.IsSlice {
+		c.toProto("m.Items", "v", m.Fields[0], "\t")
+		return b.String()
+	}
+	for _, f := range m.Fields {
+		c.toProto("m."+protoGoName(f.Name), "v."+f.goName, f, "\t")
+	}
+	return b.String()
+}
+
+// protoFromMessage is the body of a <Component>FromProto function, setting
+// the fields of v from those of m.
+func protoFromMessage(m *ProtoMessage) string {
+	var b strings.Builder
+	c := &protoConverter{b: &b}
+	if m.Component.IsSlice {
+		c.fromProto("v", "m.GetItems()", m.Fields[0], "\t")
+		return b.String()
+	}
+	for _, f := range m.Fields {
+		c.fromProto("v."+f.goName, "m.Get"+protoGoName(f.Name)+"()", f, "\t")
+	}
+	return b.String()
+}
+
+type protoConverter struct {
+	b *strings.Builder
+	// depth numbers the variables of nested loops.
+	depth int
+}
+
+func (c *protoConverter) line(indent string, format string, args ...interface{}) {
+	c.b.WriteString(indent + fmt.Sprintf(format, args...) + "\n")
+}
+
+func (c *protoConverter) vars() (string, string) {
+	c.depth++
+	if c.depth == 1 {
+		return "item", "elem"
+	}
+	return fmt.Sprintf("item%d", c.depth), fmt.Sprintf("elem%d", c.depth)
+}
+
+// toProto writes statements setting dst, a protobuf field, from src, a
+// component value, for the field f.
+func (c *protoConverter) toProto(dst string, src string, f *ProtoField, indent string) {
+	if expr, ok := toProtoExpr(src, f); ok {
+		c.line(indent, "%s = %s", dst, expr)
+		return
+	}
+
+	switch {
+	case !convertible(f):
+		c.line(indent, "// %s has no equivalent in the component", f.Name)
+	case f.Repeated:
+		item, elem := c.vars()
+		c.line(indent, "for _, %s := range %s {", item, src)
+		if expr, ok := toProtoExpr(item, f.item); ok {
+			c.line(indent+"\t", "%s = append(%s, %s)", dst, dst, expr)
+		} else {
+			c.line(indent+"\t", "var %s %s", elem, protoGoType(f.item))
+			c.toProto(elem, item, f.item, indent+"\t")
+			c.line(indent+"\t", "%s = append(%s, %s)", dst, dst, elem)
+		}
+		c.line(indent, "}")
+		c.depth--
+	case isWrapper(f):
+		c.line(indent, "%s = &pb.%s{}", dst, f.message.GoName)
+		c.toProto(dst+".Items", src, f.message.Fields[0], indent)
+	default:
+		c.line(indent, "%s = &pb.%s{}", dst, f.message.GoName)
+		for _, nf := range f.message.Fields {
+			c.toProto(dst+"."+protoGoName(nf.Name), src+"."+nf.goName, nf, indent)
+		}
+	}
+}
+
+// fromProto writes statements setting dst, a component value, from src, a
+// protobuf value, for the field f.
+func (c *protoConverter) fromProto(dst string, src string, f *ProtoField, indent string) {
+	if expr, ok := fromProtoExpr(src, f); ok {
+		c.line(indent, "%s = %s", dst, expr)
+		return
+	}
+
+	switch {
+	case !convertible(f):
+		c.line(indent, "// %s has no equivalent in the component", f.Name)
+	case f.Repeated:
+		item, elem := c.vars()
+		c.line(indent, "for _, %s := range %s {", item, src)
+		if expr, ok := fromProtoExpr(item, f.item); ok {
+			c.line(indent+"\t", "%s = append(%s, %s)", dst, dst, expr)
+		} else {
+			c.line(indent+"\t", "var %s %s", elem, ref(f.schema.Items, "protoconv"))
+			c.fromProto(elem, item, f.item, indent+"\t")
+			c.line(indent+"\t", "%s = append(%s, %s)", dst, dst, elem)
+		}
+		c.line(indent, "}")
+		c.depth--
+	case isWrapper(f):
+		c.fromProto(dst, src+".GetItems()", f.message.Fields[0], indent)
+	default:
+		for _, nf := range f.message.Fields {
+			c.fromProto(dst+"."+nf.goName, src+".Get"+protoGoName(nf.Name)+"()", nf, indent)
+		}
+	}
+}
+
+// toProtoExpr is the protobuf value of src, if a single expression
+// converts it.
+func toProtoExpr(src string, f *ProtoField) (string, bool) {
+	switch {
+	case f.Repeated:
+		return src, f.item != nil && isPlainScalar(f.item)
+	case f.message != nil:
+		return "", false
+	case f.cast:
+		return fmt.Sprintf("%s(%s)", protoGoScalar(f.Type), src), true
+	case len(f.component) > 0:
+		return fmt.Sprintf("%sToProto(%s)", f.component, src), true
+	case f.Type == "bytes":
+		return fmt.Sprintf("[]byte(%s)", src), true
+	}
+	return src, isPlainScalar(f)
+}
+
+// fromProtoExpr is the component value of src, if a single expression
+// converts it.
+func fromProtoExpr(src string, f *ProtoField) (string, bool) {
+	switch {
+	case f.Repeated:
+		return src, f.item != nil && isPlainScalar(f.item)
+	case f.message != nil:
+		return "", false
+	case f.cast:
+		return fmt.Sprintf("component.%s(%s)", f.component, src), true
+	case len(f.component) > 0:
+		return fmt.Sprintf("%sFromProto(%s)", f.component, src), true
+	case f.Type == "bytes":
+		return fmt.Sprintf("string(%s)", src), true
+	}
+	return src, isPlainScalar(f)
+}
+
+// convertible reports whether the component has an equivalent of f.
+// Variants and schemas without a type have none.
+func convertible(f *ProtoField) bool {
+	switch {
+	case f.Repeated:
+		return f.item != nil && convertible(f.item)
+	case isWrapper(f):
+		return convertible(f.message.Fields[0])
+	case f.message != nil:
+		return f.message.Oneof == nil
+	case f.cast || len(f.component) > 0:
+		return true
+	}
+	return f.schema != nil && f.schema.IsPrimitive && isScalarType(f.Type)
+}
+
+// isWrapper reports whether f is of a message nested only to hold the
+// items of an array in an array.
+func isWrapper(f *ProtoField) bool {
+	return f.message != nil && f.schema != nil && f.schema.IsSlice
+}
+
+// isPlainScalar reports whether values of f have the same Go type in the
+// component as in the message, so a slice of them can be assigned as is.
+func isPlainScalar(f *ProtoField) bool {
+	return f.message == nil && f.schema != nil && f.schema.IsPrimitive && !f.cast && len(f.component) == 0 && isScalarType(f.Type) && f.Type != "bytes"
+}
+
+func isScalarType(t string) bool {
+	switch t {
+	case "string", "bytes", "int32", "int64", "float", "double", "bool":
+		return true
+	}
+	return false
+}
+
+// protoGoScalar is the Go type protoc-gen-go uses for a scalar.
+func protoGoScalar(t string) string {
+	switch t {
+	case "bytes":
+		return "[]byte"
+	case "float":
+		return "float32"
+	case "double":
+		return "float64"
+	}
+	return t
+}
+
+// protoGoType is the Go type of a value of f in a protobuf message.
+func protoGoType(f *ProtoField) string {
+	switch {
+	case f.message != nil:
+		return "*pb." + f.message.GoName
+	case isScalarType(f.Type):
+		return protoGoScalar(f.Type)
+	case f.Type == "google.protobuf.Value":
+		return "*structpb.Value"
+	case len(f.component) > 0 && f.schema != nil && f.schema.IsPrimitive:
+		// an enum
+		return "pb." + protoGoName(f.Type)
+	}
+	return "*pb." + protoGoName(f.Type)
+}
@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProtoLock generates the .proto file of successive versions of a spec
+// into the same directory, so each one starts from the lock file of the one
+// before.
+func TestProtoLock(t *testing.T) {
+	steps := []struct {
+		name string
+		// the properties of Pet and the values of Kind
+		properties string
+		kinds      string
+		// the declarations of Pet and Kind in the .proto file
+		message string
+		enum    string
+		// the lock file's numbers for Pet
+		numbers map[string]int
+	}{
+		{
+			name:       "first",
+			properties: `{name: {type: string}, age: {type: integer}}`,
+			kinds:      `[cat, dog]`,
+			message: `message Pet {
+  int64 age = 1;
+  string name = 2;
+}`,
+			enum: `enum Kind {
+  KIND_UNSPECIFIED = 0;
+  KIND_CAT = 1; // "cat"
+  KIND_DOG = 2; // "dog"
+}`,
+			numbers: map[string]int{"age": 1, "name": 2},
+		},
+		{
+			name:       "unchanged",
+			properties: `{name: {type: string}, age: {type: integer}}`,
+			kinds:      `[cat, dog]`,
+			message: `message Pet {
+  int64 age = 1;
+  string name = 2;
+}`,
+			enum: `enum Kind {
+  KIND_UNSPECIFIED = 0;
+  KIND_CAT = 1; // "cat"
+  KIND_DOG = 2; // "dog"
+}`,
+			numbers: map[string]int{"age": 1, "name": 2},
+		},
+		{
+			name:       "field added and removed",
+			properties: `{name: {type: string}, color: {type: string}}`,
+			kinds:      `[dog, bird]`,
+			message: `message Pet {
+  reserved 1;
+  reserved "age";
+  string name = 2;
+  string color = 3;
+}`,
+			enum: `enum Kind {
+  reserved 1;
+  KIND_UNSPECIFIED = 0;
+  KIND_DOG = 2; // "dog"
+  KIND_BIRD = 3; // "bird"
+}`,
+			numbers: map[string]int{"age": 1, "name": 2, "color": 3},
+		},
+		{
+			name:       "field added back",
+			properties: `{name: {type: string}, color: {type: string}, age: {type: integer}}`,
+			kinds:      `[cat, dog, bird]`,
+			message: `message Pet {
+  int64 age = 1;
+  string name = 2;
+  string color = 3;
+}`,
+			enum: `enum Kind {
+  KIND_UNSPECIFIED = 0;
+  KIND_CAT = 1; // "cat"
+  KIND_DOG = 2; // "dog"
+  KIND_BIRD = 3; // "bird"
+}`,
+			numbers: map[string]int{"age": 1, "name": 2, "color": 3},
+		},
+	}
+
+	dir := t.TempDir()
+	for _, s := range steps {
+		t.Run(s.name, func(t *testing.T) {
+			w := walk(t, fmt.Sprintf(`
+openapi: 3.0.0
+info: {title: Pets, version: "1"}
+paths: {}
+components:
+	schemas:
+		Pet: {type: object, properties: %s}
+		Kind: {type: string, enum: %s}
+`, s.properties, s.kinds))
+
+			GenerateProtoFiles(w, Config{OutputDir: dir, PackagePath: "example.com/api"})
+			proto := readOutput(t, dir, "proto/api.proto")
+			assert.Contains(t, proto, s.message)
+			assert.Contains(t, proto, s.enum)
+
+			var lock protoLock
+			require.NoError(t, json.Unmarshal([]byte(readOutput(t, dir, protoLockFile)), &lock))
+			assert.Equal(t, s.numbers, lock.Messages["Pet"])
+		})
+	}
+}
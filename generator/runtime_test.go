@@ -0,0 +1,54 @@
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// defaultPackagePath is where the tests in testdata/runtime import the
+// generated packages from, as if they had been generated with the defaults.
+const defaultPackagePath = "github.com/mllrjb/hackathon-go-openapi-v3/generated"
+
+// TestGeneratedRuntime generates the spec.yaml of each directory in
+// testdata/runtime into a package of this module, then runs the tests of
+// that directory against it with go test, so that the runtime is checked the
+// way an application uses it.
+func TestGeneratedRuntime(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated packages")
+	}
+
+	// the packages have to be inside the module to import its dependencies
+	root, err := os.MkdirTemp("testdata", "runtime-")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	entries, err := os.ReadDir(filepath.Join("testdata", "runtime"))
+	require.NoError(t, err)
+	for _, entry := range entries {
+		from := filepath.Join("testdata", "runtime", entry.Name())
+		dir := filepath.Join(root, entry.Name())
+		packagePath := "github.com/mllrjb/hackathon-go-openapi-v3/generator/" + filepath.ToSlash(dir)
+
+		spec, err := os.ReadFile(filepath.Join(from, "spec.yaml"))
+		require.NoError(t, err)
+		GenerateFiles(walk(t, string(spec)), Config{OutputDir: dir, PackagePath: packagePath})
+
+		tests, err := filepath.Glob(filepath.Join(from, "*_test.go"))
+		require.NoError(t, err)
+		for _, test := range tests {
+			source, err := os.ReadFile(test)
+			require.NoError(t, err)
+			rewritten := strings.ReplaceAll(string(source), `"`+defaultPackagePath+`/`, `"`+packagePath+`/`)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, filepath.Base(test)), []byte(rewritten), 0644))
+		}
+	}
+
+	out, err := exec.Command("go", "test", "./"+filepath.ToSlash(root)+"/...").CombinedOutput()
+	require.NoError(t, err, "%s", out)
+}
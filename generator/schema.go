@@ -2,6 +2,7 @@ package generator
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/mllrjb/hackathon-go-openapi-v3/parser"
 	"github.com/mllrjb/hackathon-go-openapi-v3/utils"
@@ -22,18 +23,180 @@ type resolvedType struct {
 	ReferenceType string
 }
 
+// GenSchema is a schema as seen by the templates. Pkg, GoType and
+// ReferenceType come from the embedded resolvedType.
 type GenSchema struct {
 	resolvedType
-	ReceiverName       string
+	// ReceiverName is the type name for a model, or the property name for
+	// a property.
+	ReceiverName string
+	// IsDefinedElsewhere is set when the schema is a named type declared in
+	// another file, so only a reference should be rendered.
 	IsDefinedElsewhere bool
 	IsPrimitive        bool
 	IsObject           bool
 	IsSlice            bool
-	Properties         []*GenSchema
-	Items              *GenSchema
+	// Description from the spec, falling back to the title.
+	Description string
+	// Deprecated is set for `deprecated: true` schemas.
+	Deprecated bool
+	// Properties of an object, in no particular order.
+	Properties []*GenSchema
+	// Items of a slice.
+	Items *GenSchema
+	// XML is the schema's xml object, nil if it has none.
+	XML *GenXML
+
+	// Type is the schema's type, e.g. string or integer.
+	Type string
+	// Required is set on properties listed in their object's required.
+	Required bool
+	Nullable bool
+	// Format and Enum are copied from the spec. Enum values are as decoded
+	// from YAML.
+	Format string
+	Enum   []interface{}
+	// MinLength, MaxLength, MinItems and MaxItems are 0 if the spec doesn't
+	// give them.
+	MinLength int64
+	MaxLength int64
+	MinItems  int64
+	MaxItems  int64
+	// Variants are the oneOf or anyOf alternatives, and VariantsOf which of
+	// the two they came from.
+	Variants   []*GenSchema
+	VariantsOf string
+	// Discriminator is set if the variants have one.
+	Discriminator *GenDiscriminator
+	// DiscriminatorValues are set on the discriminator property of a
+	// variant component: the values that select it.
+	DiscriminatorValues []string
 }
 
-// TODO: map primitive types?
+// GenDiscriminator is the discriminator of a oneOf or anyOf schema.
+type GenDiscriminator struct {
+	PropertyName string
+	// Mapping has an entry per value, sorted by value.
+	Mapping []GenDiscriminatorMapping
+}
+
+// GenDiscriminatorMapping maps a discriminator value to the component it
+// selects.
+type GenDiscriminatorMapping struct {
+	Value     string
+	Component string
+}
+
+// SortedProperties returns the properties ordered by name, for output that
+// must not depend on map order.
+func (gs *GenSchema) SortedProperties() []*GenSchema {
+	props := append([]*GenSchema{}, gs.Properties...)
+	sort.Slice(props, func(i, j int) bool {
+		return props[i].ReceiverName < props[j].ReceiverName
+	})
+	return props
+}
+
+// GenXML adjusts how a schema is serialized as XML.
+type GenXML struct {
+	Name      string
+	Namespace string
+	// Prefix is kept for templates; encoding/xml can't write prefixes.
+	Prefix    string
+	Attribute bool
+	Wrapped   bool
+}
+
+func generateXML(m parser.SchemaModel) *GenXML {
+	x := m.GetXML()
+	if x == nil {
+		return nil
+	}
+	return &GenXML{
+		Name:      x.Name,
+		Namespace: x.Namespace,
+		Prefix:    x.Prefix,
+		Attribute: x.Attribute,
+		Wrapped:   x.Wrapped,
+	}
+}
+
+// setXML copies the xml objects of m, and of its items if it is an array,
+// onto gs.
+func setXML(gs *GenSchema, m parser.SchemaModel) {
+	gs.XML = generateXML(m)
+	if a, ok := m.(*parser.ArraySchemaModel); ok && gs.Items != nil && gs.Items.XML == nil {
+		gs.Items.XML = generateXML(a.Items)
+	}
+}
+
+// xmlTag is the xml struct tag of a property: its element (or attribute)
+// name, with the namespace if any. Arrays are repeated elements named after
+// their items, or wrapped in an element named after the property.
+//
+// encoding/xml writes the namespace of a wrapper>item tag on the items but
+// reads it from the wrapper, so a wrapped array's items are written in the
+// namespace they would inherit from the wrapper, unless they have their own,
+// and documents with the namespace on the wrapper are read.
+func xmlTag(gs *GenSchema) string {
+	name := gs.ReceiverName
+	x := gs.XML
+	if x == nil {
+		x = &GenXML{}
+	}
+	if len(x.Name) > 0 {
+		name = x.Name
+	}
+	namespace := x.Namespace
+
+	if gs.IsSlice && gs.Items != nil {
+		itemName := ""
+		if gs.Items.XML != nil {
+			itemName = gs.Items.XML.Name
+			if len(gs.Items.XML.Namespace) > 0 {
+				namespace = gs.Items.XML.Namespace
+			}
+		}
+		if x.Wrapped {
+			if len(itemName) == 0 {
+				itemName = name
+			}
+			name = name + ">" + itemName
+		} else if len(itemName) > 0 {
+			name = itemName
+		}
+	}
+
+	if len(namespace) > 0 {
+		name = namespace + " " + name
+	}
+	if x.Attribute {
+		name += ",attr"
+	}
+	return name
+}
+
+// xmlRoot is the name of the root element for a body, which encoding/xml
+// would otherwise take from the Go type: the xml name, else the component
+// name. itemName is the same for the items of an array.
+func xmlRoot(m parser.SchemaModel) (namespace string, name string, itemName string) {
+	if m == nil {
+		return "", "", ""
+	}
+	name = m.GetComponentName()
+	if x := m.GetXML(); x != nil {
+		namespace = x.Namespace
+		if len(x.Name) > 0 {
+			name = x.Name
+		}
+	}
+	if a, ok := m.(*parser.ArraySchemaModel); ok {
+		_, itemName, _ = xmlRoot(a.Items)
+	}
+	return namespace, name, itemName
+}
+
+// getPrimitiveType is the Go type of a primitive schema of type t.
 func getPrimitiveType(t string, format string) string {
 	switch t {
 	case "integer":
@@ -41,6 +204,13 @@ func getPrimitiveType(t string, format string) string {
 			return format
 		}
 		return "int64"
+	case "number":
+		if format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
 	}
 	return t
 }
@@ -102,10 +272,110 @@ func getResolvedType(m parser.SchemaModel, pkg string) resolvedType {
 }
 
 func GenerateSchema(m parser.SchemaModel, receiverName string, pkg string) GenSchema {
-	if m.IsDiscriminated() {
-		fmt.Sprintf("discriminated: %s\v", m.GetComponentName())
+	gs := generateSchema(m, receiverName, pkg)
+	gs.Description = m.GetDescription()
+	gs.Deprecated = m.IsDeprecated()
+	setXML(&gs, m)
+	setConstraints(&gs, m, pkg)
+	return gs
+}
+
+// setFileParts makes the binary string properties of a multipart/form-data
+// body *FilePart, and its arrays of them *FileParts, so the router can
+// stream the uploaded files to the handler (see form.go.tmpl).
+func setFileParts(gs *GenSchema) {
+	isFile := func(p *GenSchema) bool {
+		return p.IsPrimitive && !p.IsDefinedElsewhere && p.Type == "string" && p.Format == "binary"
+	}
+	for _, p := range gs.Properties {
+		if isFile(p) {
+			p.GoType = "*FilePart"
+		} else if p.IsSlice && p.Items != nil && isFile(p.Items) {
+			p.IsSlice = false
+			p.IsPrimitive = true
+			p.GoType = "*FileParts"
+		}
+	}
+}
+
+// setConstraints copies the validation keywords of m onto gs, and generates
+// its oneOf or anyOf variants.
+func setConstraints(gs *GenSchema, m parser.SchemaModel, pkg string) {
+	gs.Type = m.GetType()
+	switch t := m.(type) {
+	case *parser.PrimitiveSchemaModel:
+		gs.Nullable = t.Nullable
+		gs.Enum = t.Enum
+		gs.Format = t.Format
+		gs.MinLength = t.MinLength
+		gs.MaxLength = t.MaxLength
+	case *parser.ArraySchemaModel:
+		gs.Nullable = t.Nullable
+		gs.Enum = t.Enum
+		gs.MinItems = t.MinItems
+		gs.MaxItems = t.MaxItems
+	case *parser.StructSchemaModel:
+		gs.Nullable = t.Nullable
+		gs.Enum = t.Enum
+		for _, prop := range gs.Properties {
+			prop.Required = contains(t.Required, prop.ReceiverName)
+		}
+	}
+
+	d := m.GetDiscriminator()
+	if d == nil || !d.IsDiscriminated() {
+		return
+	}
+	gs.VariantsOf = d.DiscriminatorType
+	for i, variant := range d.DiscriminatorSchemas {
+		name := fmt.Sprintf("%sVariant%d", gs.ReceiverName, i+1)
+		gv := GenerateSchema(variant, name, pkg)
+		gs.Variants = append(gs.Variants, &gv)
+	}
+	if d.Discriminator != nil {
+		gd := &GenDiscriminator{PropertyName: d.Discriminator.PropertyName}
+		for value, variant := range d.Discriminator.Mapping {
+			if variant.IsComponent() {
+				gd.Mapping = append(gd.Mapping, GenDiscriminatorMapping{Value: value, Component: variant.GetComponentName()})
+			}
+		}
+		sort.Slice(gd.Mapping, func(i, j int) bool {
+			return gd.Mapping[i].Value < gd.Mapping[j].Value
+		})
+		gs.Discriminator = gd
+	}
+}
+
+// setDiscriminatorValues records, on the discriminator property of every
+// variant component, the values that select it. Properties merged in from
+// an allOf are matched by their PascalCase name.
+func setDiscriminatorValues(schemas []*GenSchema) {
+	byName := map[string]*GenSchema{}
+	for _, gs := range schemas {
+		byName[gs.ReceiverName] = gs
 	}
 
+	for _, gs := range schemas {
+		if gs.Discriminator == nil {
+			continue
+		}
+		for _, mapping := range gs.Discriminator.Mapping {
+			variant, ok := byName[mapping.Component]
+			if !ok {
+				continue
+			}
+			for _, prop := range variant.Properties {
+				if prop.ReceiverName == gs.Discriminator.PropertyName || prop.ReceiverName == utils.ToPascalCase(gs.Discriminator.PropertyName) {
+					if !contains(prop.DiscriminatorValues, mapping.Value) {
+						prop.DiscriminatorValues = append(prop.DiscriminatorValues, mapping.Value)
+					}
+				}
+			}
+		}
+	}
+}
+
+func generateSchema(m parser.SchemaModel, receiverName string, pkg string) GenSchema {
 	resolvedType := getResolvedType(m, pkg)
 	if m.IsPrimitive() {
 		p := m.(*parser.PrimitiveSchemaModel)
@@ -196,6 +466,15 @@ func GenerateSchema(m parser.SchemaModel, receiverName string, pkg string) GenSc
 }
 
 func GenerateSchemaComponents(m parser.SchemaModel) GenSchema {
+	gs := generateSchemaComponents(m)
+	gs.Description = m.GetDescription()
+	gs.Deprecated = m.IsDeprecated()
+	setXML(&gs, m)
+	setConstraints(&gs, m, "component")
+	return gs
+}
+
+func generateSchemaComponents(m parser.SchemaModel) GenSchema {
 	if m.IsDiscriminated() {
 		fmt.Printf("discriminated: %s\n", m.GetComponentName())
 	}
@@ -294,3 +573,23 @@ func GetAllNestedModels(gs *GenSchema) []*GenSchema {
 
 	return []*GenSchema{}
 }
+
+// referencedPackages adds every generated package, other than current, that
+// gs refers to.
+func referencedPackages(gs *GenSchema, current string, pkgs map[string]bool) {
+	if gs == nil {
+		return
+	}
+
+	if gs.IsDefinedElsewhere {
+		if gs.Pkg != current && len(gs.Pkg) > 0 {
+			pkgs[gs.Pkg] = true
+		}
+		return
+	}
+
+	for _, p := range gs.Properties {
+		referencedPackages(p, current, pkgs)
+	}
+	referencedPackages(gs.Items, current, pkgs)
+}
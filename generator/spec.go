@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/parser"
+)
+
+// generateSpec writes the bundled document as openapi.json and openapi.yaml
+// to outputDir, where spec.go.tmpl embeds them.
+func generateSpec(walker parser.Walker, outputDir string) error {
+	bundle := walker.Bundle()
+
+	jsonBytes, err := json.MarshalIndent(jsonValue(bundle), "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode spec as JSON: %v", err)
+	}
+	err = writeFile(fmt.Sprintf("%s/openapi.json", outputDir), append(jsonBytes, '\n'))
+	if err != nil {
+		return err
+	}
+
+	yamlBytes, err := yaml.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("unable to encode spec as YAML: %v", err)
+	}
+	return writeFile(fmt.Sprintf("%s/openapi.yaml", outputDir), yamlBytes)
+}
+
+// orderedObject marshals a YAML mapping as a JSON object with its keys in
+// their original order.
+type orderedObject yaml.MapSlice
+
+func (m orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, item := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		// YAML keys needn't be strings, e.g. unquoted status codes
+		key, err := json.Marshal(fmt.Sprint(item.Key))
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(jsonValue(item.Value))
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonValue converts a value decoded from YAML into one that encoding/json
+// can marshal.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		return orderedObject(v)
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = jsonValue(item)
+		}
+		return items
+	}
+	return value
+}
@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/templates"
+)
+
+// supportFile is a template that is rendered once per spec (rather than once
+// per operation or component).
+type supportFile struct {
+	Template string
+	Output   string
+}
+
+var supportFiles = []supportFile{
+	{Template: "pathRouting.tmpl", Output: "pathRouting.go"},
+	{Template: "server.tmpl", Output: "server.go"},
+	{Template: "middleware.tmpl", Output: "middleware.go"},
+	{Template: "security.tmpl", Output: "security.go"},
+	{Template: "router.go.tmpl", Output: "router.go"},
+	{Template: "responder.go.tmpl", Output: "operation/responder.go"},
+	{Template: "binder.go.tmpl", Output: "binder.go"},
+	{Template: "form.go.tmpl", Output: "operation/form.go"},
+	{Template: "problem.go.tmpl", Output: "operation/problem.go"},
+	{Template: "spec.go.tmpl", Output: "spec.go"},
+	{Template: "validation.go.tmpl", Output: "validation.go"},
+	{Template: "mock.go.tmpl", Output: "mock.go"},
+	{Template: "arbitrary.go.tmpl", Output: "component/arbitrary.go"},
+}
+
+// Templates is the parsed template set, plus any support files that an
+// override directory supplied as plain .go files to be copied verbatim.
+type Templates struct {
+	*template.Template
+	Verbatim map[string]string
+}
+
+// LoadTemplates parses the embedded default templates, then layers the
+// contents of overrideDir (if any) on top of them.
+//
+// A .tmpl file in overrideDir with the same name as a default replaces it
+// entirely. Any {{define "name"}} block in an override file replaces the
+// default block of the same name, so small changes don't need a full copy.
+// A plain .go file (e.g. responder.go) replaces the matching support
+// template and is copied to the output without being executed.
+func LoadTemplates(overrideDir string, funcMap template.FuncMap) (*Templates, error) {
+	t, err := template.New("template").Funcs(funcMap).ParseFS(templates.FS, "*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse default templates: %v", err)
+	}
+
+	tmpls := &Templates{
+		Template: t,
+		Verbatim: map[string]string{},
+	}
+
+	if len(overrideDir) == 0 {
+		return tmpls, nil
+	}
+
+	files, err := ioutil.ReadDir(overrideDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read template directory: %v", err)
+	}
+
+	var overrides []string
+	for _, file := range files {
+		filename := file.Name()
+		fullPath := filepath.Join(overrideDir, filename)
+		if strings.HasSuffix(filename, ".tmpl") {
+			overrides = append(overrides, fullPath)
+		} else if strings.HasSuffix(filename, ".go") {
+			tmpls.Verbatim[filename+".tmpl"] = fullPath
+		}
+	}
+
+	if len(overrides) > 0 {
+		_, err = t.ParseFiles(overrides...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse override templates: %v", err)
+		}
+	}
+
+	return tmpls, nil
+}
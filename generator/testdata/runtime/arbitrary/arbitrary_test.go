@@ -0,0 +1,119 @@
+package generated
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/component"
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+// server answers addPet with response.
+type server struct {
+	response *operation.Responder
+}
+
+func (s server) AddPet(ctx context.Context, params operation.AddPetParameters, body component.Pet) (operation.Responder, error) {
+	return *s.response, nil
+}
+
+// created writes a 201 with a body of any JSON, which the Go types can't
+// hold when it is invalid.
+type created json.RawMessage
+
+func (r created) WriteResponse(writer http.ResponseWriter) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusCreated)
+	writer.Write(r)
+}
+
+// validator returns a func that checks a response against the spec.
+func validator(t *testing.T) func(response operation.Responder) *httptest.ResponseRecorder {
+	var response operation.Responder
+	router := NewRouter(server{response: &response}, WithResponseValidation(FailResponseViolations))
+	return func(r operation.Responder) *httptest.ResponseRecorder {
+		response = r
+		req := httptest.NewRequest("POST", "/pets", strings.NewReader(`{"id": 1, "name": "rex"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+}
+
+func TestArbitraryValid(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	validate := validator(t)
+
+	for i := 0; i < 50; i++ {
+		pet := component.ArbitraryPet(random)
+		res := validate(operation.NewAddPet201Response(pet))
+		assert.Equal(t, http.StatusCreated, res.Code, "%+v: %s", pet, res.Body.String())
+	}
+}
+
+func TestArbitraryDeterministic(t *testing.T) {
+	a := component.ArbitraryPet(rand.New(rand.NewSource(7)))
+	b := component.ArbitraryPet(rand.New(rand.NewSource(7)))
+	assert.Equal(t, a, b)
+}
+
+func TestInvalid(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	validate := validator(t)
+
+	constraints := []string{}
+	for _, instance := range component.InvalidPet(random) {
+		constraints = append(constraints, instance.Constraint+" "+instance.Pointer)
+
+		res := validate(created(instance.JSON))
+		if assert.Equal(t, http.StatusInternalServerError, res.Code, "%s breaks %s", instance.JSON, instance.Constraint) {
+			var problem operation.Problem
+			require.NoError(t, json.Unmarshal(res.Body.Bytes(), &problem))
+			require.NotEmpty(t, problem.Errors)
+			if len(instance.Pointer) > 0 && instance.Constraint != "required" {
+				assert.Equal(t, instance.Pointer, problem.Errors[0].Pointer, "%s", instance.JSON)
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"type ",
+		"required /id",
+		"type /id",
+		"required /name",
+		"type /name",
+		"minLength /name",
+		"maxLength /name",
+		"type /kind",
+		"enum /kind",
+		"type /chip",
+		"format /chip",
+		"type /owner",
+		"format /owner",
+		"type /born",
+		"format /born",
+		"type /home",
+		"format /home",
+		"type /host",
+		"format /host",
+		"type /ip",
+		"format /ip",
+		"type /ip6",
+		"format /ip6",
+		"type /photo",
+		"format /photo",
+		"type /feeding",
+		"format /feeding",
+		"type /tags",
+		"maxItems /tags",
+		"type /tags/0",
+	}, constraints)
+}
@@ -0,0 +1,347 @@
+package generated
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+// upload is what the handler read from a multipart body.
+type upload struct {
+	name        string
+	tags        []string
+	size        int64
+	photo       string
+	filename    string
+	contentType string
+	attachments []string
+}
+
+// server records the bodies it is given, reading any files while the handler
+// runs.
+type server struct {
+	Unimplemented
+	upload *upload
+	search *operation.SearchForm
+	// photo is kept to check it is closed after the handler
+	photo *operation.FilePart
+	// called, if set, is closed when the handler is called
+	called chan struct{}
+}
+
+func (s *server) Upload_Multipart(ctx context.Context, params operation.UploadParameters, body operation.UploadMultipart) (operation.Responder, error) {
+	if s.called != nil {
+		close(s.called)
+	}
+	photo, err := io.ReadAll(body.Photo)
+	if err != nil {
+		return nil, err
+	}
+	s.photo = body.Photo
+	u := &upload{
+		name:        body.Name,
+		tags:        body.Tags,
+		size:        body.Meta.Size,
+		photo:       string(photo),
+		filename:    body.Photo.Filename,
+		contentType: body.Photo.ContentType,
+	}
+	for {
+		attachment, err := body.Attachments.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(attachment)
+		if err != nil {
+			return nil, err
+		}
+		u.attachments = append(u.attachments, string(b))
+	}
+	s.upload = u
+	return operation.StatusCodeResponder(http.StatusOK), nil
+}
+
+func (s *server) Search_Form(ctx context.Context, params operation.SearchParameters, body operation.SearchForm) (operation.Responder, error) {
+	s.search = &body
+	return operation.StatusCodeResponder(http.StatusOK), nil
+}
+
+// part is one part of a multipart body.
+type part struct {
+	name        string
+	filename    string
+	contentType string
+	content     string
+}
+
+// writePart writes p to w.
+func writePart(w *multipart.Writer, p part) error {
+	header := textproto.MIMEHeader{}
+	disposition := `form-data; name="` + p.name + `"`
+	if len(p.filename) > 0 {
+		disposition += `; filename="` + p.filename + `"`
+	}
+	header.Set("Content-Disposition", disposition)
+	if len(p.contentType) > 0 {
+		header.Set("Content-Type", p.contentType)
+	}
+	pw, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = pw.Write([]byte(p.content))
+	return err
+}
+
+func multipartRequest(t *testing.T, parts ...part) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, p := range parts {
+		require.NoError(t, writePart(w, p))
+	}
+	require.NoError(t, w.Close())
+
+	req := httptest.NewRequest("POST", "/uploads", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func serve(handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
+
+func TestMultipart(t *testing.T) {
+	photo := strings.Repeat("png", 1000)
+	s := &server{}
+	// files don't count towards the memory limit
+	router := NewRouter(s, WithMaxFormMemory(100))
+
+	res := serve(router, multipartRequest(t,
+		part{name: "name", content: "rex"},
+		part{name: "tags", content: "dog"},
+		part{name: "tags", content: "good"},
+		part{name: "meta", contentType: "application/json", content: `{"size": 3}`},
+		part{name: "photo", filename: "rex.png", contentType: "image/png", content: photo},
+		part{name: "attachments", filename: "a.txt", content: "a"},
+		part{name: "attachments", filename: "b.txt", content: "b"},
+	))
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	assert.Equal(t, &upload{
+		name:        "rex",
+		tags:        []string{"dog", "good"},
+		size:        3,
+		photo:       photo,
+		filename:    "rex.png",
+		contentType: "image/png",
+		attachments: []string{"a", "b"},
+	}, s.upload)
+
+	// the files are closed once the handler returns
+	n, err := s.photo.Read(make([]byte, 1))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestMultipartErrors(t *testing.T) {
+	photo := part{name: "photo", filename: "rex.png", contentType: "image/png", content: "png"}
+	name := part{name: "name", content: "rex"}
+
+	cases := []struct {
+		name   string
+		req    func(t *testing.T) *http.Request
+		opts   []RouterOption
+		status int
+		// the fields that failed to bind
+		fields []string
+	}{
+		{
+			name:   "missing required file",
+			req:    func(t *testing.T) *http.Request { return multipartRequest(t, name) },
+			status: http.StatusBadRequest,
+			fields: []string{"photo"},
+		},
+		{
+			name: "file of the wrong content type",
+			req: func(t *testing.T) *http.Request {
+				return multipartRequest(t, name, part{name: "photo", filename: "rex.txt", contentType: "text/plain", content: "rex"})
+			},
+			status: http.StatusBadRequest,
+			fields: []string{"photo"},
+		},
+		{
+			name: "invalid JSON part",
+			req: func(t *testing.T) *http.Request {
+				return multipartRequest(t, name, part{name: "meta", contentType: "application/json", content: "{"}, photo)
+			},
+			status: http.StatusBadRequest,
+			fields: []string{"meta"},
+		},
+		{
+			name:   "field after the files",
+			req:    func(t *testing.T) *http.Request { return multipartRequest(t, photo, name) },
+			status: http.StatusBadRequest,
+			fields: []string{"name"},
+		},
+		{
+			name: "fields over the memory limit",
+			req: func(t *testing.T) *http.Request {
+				return multipartRequest(t, part{name: "name", content: strings.Repeat("rex", 100)}, photo)
+			},
+			opts:   []RouterOption{WithMaxFormMemory(100)},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "malformed body",
+			req: func(t *testing.T) *http.Request {
+				req := httptest.NewRequest("POST", "/uploads", strings.NewReader("not multipart"))
+				req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+				return req
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "too large",
+			req: func(t *testing.T) *http.Request {
+				return multipartRequest(t, name, part{name: "photo", filename: "rex.png", contentType: "image/png", content: strings.Repeat("png", 100)})
+			},
+			opts:   []RouterOption{WithMaxFormBytes(100)},
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var bindErr error
+			opts := append([]RouterOption{WithErrorMapper(func(ctx context.Context, err error) operation.Responder {
+				bindErr = err
+				return operation.DefaultErrorMapper(ctx, err)
+			})}, c.opts...)
+			s := &server{}
+
+			res := serve(NewRouter(s, opts...), c.req(t))
+			assert.Equal(t, c.status, res.Code)
+			assert.Nil(t, s.upload, "the handler was called")
+
+			if len(c.fields) > 0 {
+				var errs ParameterErrors
+				require.True(t, errors.As(bindErr, &errs), "%v", bindErr)
+				fields := []string{}
+				for _, err := range errs {
+					fields = append(fields, err.Name)
+				}
+				assert.Equal(t, c.fields, fields)
+			}
+		})
+	}
+}
+
+func TestMultipartStreaming(t *testing.T) {
+	body, bodyWriter := io.Pipe()
+	w := multipart.NewWriter(bodyWriter)
+	s := &server{called: make(chan struct{})}
+
+	go func() {
+		writePart(w, part{name: "name", content: "rex"})
+		photo, _ := w.CreatePart(textproto.MIMEHeader{
+			"Content-Disposition": {`form-data; name="photo"; filename="rex.png"`},
+			"Content-Type":        {"image/png"},
+		})
+		photo.Write([]byte("pn"))
+		// the handler runs before the rest of the photo has been sent
+		select {
+		case <-s.called:
+		case <-time.After(5 * time.Second):
+			t.Error("the handler wasn't called before the whole photo was sent")
+		}
+		photo.Write([]byte("g"))
+		w.Close()
+		bodyWriter.Close()
+	}()
+
+	req := httptest.NewRequest("POST", "/uploads", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	res := serve(NewRouter(s), req)
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	assert.Equal(t, "png", s.upload.photo)
+}
+
+func TestMultipartFileErrors(t *testing.T) {
+	name := part{name: "name", content: "rex"}
+	photo := part{name: "photo", filename: "rex.png", contentType: "image/png", content: "png"}
+	attachment := part{name: "attachments", filename: "a.txt", content: "a"}
+
+	cases := []struct {
+		name  string
+		parts []part
+		// the error the handler returned
+		detail string
+	}{
+		{
+			name:   "skipped file",
+			parts:  []part{name, attachment, photo},
+			detail: "attachments was sent before a file that was read first",
+		},
+		{
+			name:   "missing file",
+			parts:  []part{name, attachment},
+			detail: "photo: file is missing",
+		},
+		{
+			name:   "file of the wrong content type",
+			parts:  []part{name, attachment, {name: "photo", filename: "rex.txt", contentType: "text/plain", content: "rex"}},
+			detail: `photo: content type "text/plain" is not allowed, expected image/*`,
+		},
+		{
+			name:   "field between the files",
+			parts:  []part{name, photo, {name: "tags", content: "dog"}, attachment},
+			detail: "tags was sent after a file, so it isn't bound",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &server{}
+			res := serve(NewRouter(s), multipartRequest(t, c.parts...))
+			require.Equal(t, http.StatusBadRequest, res.Code, res.Body.String())
+			assert.Nil(t, s.upload)
+			var problem operation.Problem
+			require.NoError(t, json.Unmarshal(res.Body.Bytes(), &problem))
+			assert.Equal(t, c.detail, problem.Detail)
+		})
+	}
+}
+
+func TestURLEncoded(t *testing.T) {
+	s := &server{}
+	router := NewRouter(s)
+
+	req := httptest.NewRequest("POST", "/search", strings.NewReader("query=rex&limit=5&tags=dog,good"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res := serve(router, req)
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	assert.Equal(t, &operation.SearchForm{Query: "rex", Limit: 5, Tags: []string{"dog", "good"}}, s.search)
+
+	req = httptest.NewRequest("POST", "/search", strings.NewReader("limit=x"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res = serve(router, req)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
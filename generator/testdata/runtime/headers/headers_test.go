@@ -0,0 +1,98 @@
+package generated
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/component"
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+// server answers addPet with the responder it is given.
+type server struct {
+	Unimplemented
+	responder operation.Responder
+}
+
+func (s server) AddPet(ctx context.Context, params operation.AddPetParameters, body component.Pet) (operation.Responder, error) {
+	return s.responder, nil
+}
+
+func post(responder operation.Responder) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/pets", strings.NewReader(`{"name": "rex"}`))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+	NewRouter(server{responder: responder}).ServeHTTP(res, req)
+	return res
+}
+
+func TestTypedResponses(t *testing.T) {
+	pet := component.Pet{Name: "rex"}
+
+	cases := []struct {
+		name      string
+		responder operation.Responder
+		status    int
+		headers   http.Header
+		body      string
+	}{
+		{
+			name:      "required header",
+			responder: operation.NewAddPet201Response("/pets/rex", pet),
+			status:    http.StatusCreated,
+			headers:   http.Header{"Location": {"/pets/rex"}},
+			body:      `{"name":"rex"}`,
+		},
+		{
+			name:      "optional headers",
+			responder: operation.NewAddPet201Response("/pets/rex", pet).WithXRateLimit(10).WithXTags([]string{"dog", "good"}),
+			status:    http.StatusCreated,
+			headers: http.Header{
+				"Location":     {"/pets/rex"},
+				"X-Rate-Limit": {"10"},
+				"X-Tags":       {"dog,good"},
+			},
+			body: `{"name":"rex"}`,
+		},
+		{
+			name:      "no content",
+			responder: operation.NewAddPet204Response(),
+			status:    http.StatusNoContent,
+			headers:   http.Header{},
+		},
+		{
+			name:      "default",
+			responder: operation.NewAddPetDefaultResponse(http.StatusConflict, component.Error{Message: "taken"}),
+			status:    http.StatusConflict,
+			headers:   http.Header{},
+			body:      `{"message":"taken"}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res := post(c.responder)
+			require.Equal(t, c.status, res.Code, res.Body.String())
+			for name := range c.headers {
+				assert.Equal(t, c.headers[name], res.Header().Values(name), name)
+			}
+			for _, name := range []string{"Location", "X-Rate-Limit", "X-Tags"} {
+				if _, ok := c.headers[name]; !ok {
+					assert.Empty(t, res.Header().Values(name), name)
+				}
+			}
+			if len(c.body) > 0 {
+				assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+				assert.JSONEq(t, c.body, res.Body.String())
+			} else {
+				assert.Empty(t, res.Body.String())
+			}
+		})
+	}
+}
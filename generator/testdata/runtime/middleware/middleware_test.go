@@ -0,0 +1,136 @@
+package generated
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+// server records the operation each request was routed to.
+type server struct {
+	Unimplemented
+	ops []OperationInfo
+}
+
+func (s *server) ListPets(ctx context.Context, params operation.ListPetsParameters) (operation.Responder, error) {
+	return s.record(ctx)
+}
+
+func (s *server) GetPet(ctx context.Context, params operation.GetPetParameters) (operation.Responder, error) {
+	return s.record(ctx)
+}
+
+func (s *server) record(ctx context.Context) (operation.Responder, error) {
+	op, _ := OperationFromContext(ctx)
+	s.ops = append(s.ops, op)
+	return operation.StatusCodeResponder(http.StatusOK), nil
+}
+
+// trace is middleware that adds name to the X-Trace header of the response
+// before calling the next handler.
+func trace(name string) Middleware {
+	return func(op OperationInfo, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.Header().Add("X-Trace", name+":"+op.OperationID)
+			next.ServeHTTP(res, req)
+		})
+	}
+}
+
+// anyKey accepts every API key.
+var anyKey = WithAuthenticator("key", AuthenticatorFunc(func(ctx context.Context, creds Credentials, scopes []string) (interface{}, error) {
+	return creds.APIKey, nil
+}))
+
+// get sends a GET with an API key to router.
+func get(router http.Handler, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("X-API-Key", "secret")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	router := NewRouter(&server{},
+		anyKey,
+		WithOperationMiddleware("listPets", trace("list")),
+		WithMiddleware(trace("first"), trace("second")),
+		WithMiddleware(HTTPMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				res.Header().Add("X-Trace", "plain")
+				next.ServeHTTP(res, req)
+			})
+		})),
+	)
+
+	cases := []struct {
+		path  string
+		trace []string
+	}{
+		{path: "/pets", trace: []string{"first:listPets", "second:listPets", "plain", "list:listPets"}},
+		{path: "/pets/1", trace: []string{"first:getPet", "second:getPet", "plain"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			res := get(router, c.path)
+			require.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, c.trace, res.Header().Values("X-Trace"))
+		})
+	}
+
+	// authentication comes after the middleware
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest("GET", "/pets/1", nil))
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Equal(t, []string{"first:getPet", "second:getPet", "plain"}, res.Header().Values("X-Trace"))
+}
+
+func TestMiddlewareBuiltOnce(t *testing.T) {
+	built := []string{}
+	router := NewRouter(&server{}, WithMiddleware(func(op OperationInfo, next http.Handler) http.Handler {
+		built = append(built, op.OperationID)
+		return next
+	}))
+
+	for i := 0; i < 3; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/pets", nil))
+	}
+	assert.ElementsMatch(t, []string{"listPets", "getPet"}, built)
+}
+
+func TestOperationFromContext(t *testing.T) {
+	s := &server{}
+	router := NewRouter(s, anyKey)
+	get(router, "/pets")
+	get(router, "/pets/1")
+
+	assert.Equal(t, []OperationInfo{
+		{
+			OperationID: "listPets",
+			Method:      "GET",
+			Path:        "/pets",
+			Tags:        []string{"pets"},
+			Security:    []SecurityRequirement{{"token": {"read", "write"}}, {}},
+		},
+		{
+			OperationID: "getPet",
+			Method:      "GET",
+			Path:        "/pets/{id}",
+			Tags:        []string{"pets", "detail"},
+			// the document's requirement
+			Security: []SecurityRequirement{{"key": {}}},
+		},
+	}, s.ops)
+
+	_, ok := OperationFromContext(context.Background())
+	assert.False(t, ok)
+	assert.Equal(t, Operations["getPet"], s.ops[1])
+}
@@ -0,0 +1,122 @@
+package generated
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+// problemOf decodes a problem+json response.
+func problemOf(t *testing.T, res *httptest.ResponseRecorder) operation.Problem {
+	require.Equal(t, operation.ProblemContentType, res.Header().Get("Content-Type"), res.Body.String())
+	var problem operation.Problem
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &problem))
+	return problem
+}
+
+func addPet(router http.Handler, prefer string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/pets", strings.NewReader(`{"id": 1, "name": "rex"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if len(prefer) > 0 {
+		req.Header.Set("Prefer", prefer)
+	}
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func TestMockExamples(t *testing.T) {
+	router := NewRouter(MockServer{}, WithResponseValidation(FailResponseViolations))
+
+	cases := []struct {
+		name   string
+		prefer string
+		status int
+		body   string
+	}{
+		{name: "first example", status: http.StatusCreated, body: `{"id": 1, "name": "rex", "kind": "dog"}`},
+		{name: "named example", prefer: "example=tom", status: http.StatusCreated, body: `{"id": 2, "name": "tom", "kind": "cat"}`},
+		{name: "status code", prefer: "code=409", status: http.StatusConflict, body: `{"message": "the name is taken"}`},
+		{name: "default response", prefer: "code=500", status: http.StatusInternalServerError, body: `{"message": "string"}`},
+		{name: "both", prefer: "code=201, example=tom", status: http.StatusCreated, body: `{"id": 2, "name": "tom", "kind": "cat"}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res := addPet(router, c.prefer)
+			require.Equal(t, c.status, res.Code, res.Body.String())
+			assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+			assert.JSONEq(t, c.body, res.Body.String())
+			if c.status == http.StatusCreated {
+				assert.NotEmpty(t, res.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestMockFromSchema(t *testing.T) {
+	router := NewRouter(MockServer{}, WithResponseValidation(FailResponseViolations))
+	get := func() *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest("GET", "/events/1", nil))
+		return res
+	}
+
+	res := get()
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	assert.Equal(t, "00000000-0000-4000-8000-000000000000", res.Header().Get("X-Request-Id"))
+	assert.JSONEq(t, `{
+		"id": "00000000-0000-4000-8000-000000000000",
+		"at": "2024-01-01T00:00:00Z",
+		"by": "user@example.com",
+		"kind": "created",
+		"code": "stringxxxxxx",
+		"count": 5,
+		"note": "hello",
+		"tags": ["string", "string"]
+	}`, res.Body.String())
+
+	// the same request always gets the same body
+	assert.Equal(t, res.Body.String(), get().Body.String())
+}
+
+func TestMockUndeclared(t *testing.T) {
+	// the problems aren't declared by the operations, so they would fail
+	// validation
+	router := NewRouter(MockServer{})
+
+	cases := []struct {
+		method string
+		path   string
+		prefer string
+		detail string
+	}{
+		{method: "POST", path: "/pets", prefer: "example=felix", detail: `example "felix" is not declared`},
+		// getEvent has no default response to fall back on
+		{method: "GET", path: "/events/1", prefer: "code=404", detail: "status code 404 is not declared"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.prefer, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, strings.NewReader(`{"id": 1, "name": "rex"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Prefer", c.prefer)
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			require.Equal(t, http.StatusBadRequest, res.Code)
+			assert.Equal(t, c.detail, problemOf(t, res).Detail)
+		})
+	}
+
+	// a default response stands in for any code
+	res := addPet(router, "code=404")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.JSONEq(t, `{"message": "string"}`, res.Body.String())
+}
@@ -0,0 +1,150 @@
+package generated
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/component"
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+// server records the parameters each operation was called with.
+type server struct {
+	params interface{}
+}
+
+func (s *server) GetLabel(ctx context.Context, params operation.GetLabelParameters) (operation.Responder, error) {
+	return s.record(params)
+}
+
+func (s *server) GetMatrix(ctx context.Context, params operation.GetMatrixParameters) (operation.Responder, error) {
+	return s.record(params)
+}
+
+func (s *server) GetSimple(ctx context.Context, params operation.GetSimpleParameters) (operation.Responder, error) {
+	return s.record(params)
+}
+
+func (s *server) GetQuery(ctx context.Context, params operation.GetQueryParameters) (operation.Responder, error) {
+	return s.record(params)
+}
+
+func (s *server) GetHeader(ctx context.Context, params operation.GetHeaderParameters) (operation.Responder, error) {
+	return s.record(params)
+}
+
+func (s *server) record(params interface{}) (operation.Responder, error) {
+	s.params = params
+	return operation.StatusCodeResponder(http.StatusOK), nil
+}
+
+func TestParameterStyles(t *testing.T) {
+	cases := []struct {
+		name    string
+		path    string
+		headers http.Header
+		cookie  string
+		params  interface{}
+	}{
+		{
+			name:   "label",
+			path:   "/label/.1,2,3",
+			params: operation.GetLabelParameters{Ids: []int64{1, 2, 3}},
+		},
+		{
+			name:   "exploded matrix object",
+			path:   "/matrix/;row=1;col=2",
+			params: operation.GetMatrixParameters{Point: component.Point{Row: 1, Col: 2}},
+		},
+		{
+			name:   "simple object",
+			path:   "/simple/row,1,col,2",
+			params: operation.GetSimpleParameters{Point: component.Point{Row: 1, Col: 2}},
+		},
+		{
+			name: "query styles",
+			path: "/query?ids=1&ids=2&names=a,b&spaced=c%20d&piped=e|f&filter[status]=open&filter[min]=2&limit=10",
+			params: operation.GetQueryParameters{
+				Ids:    []int64{1, 2},
+				Names:  []string{"a", "b"},
+				Spaced: []string{"c", "d"},
+				Piped:  []string{"e", "f"},
+				Filter: component.Filter{Status: "open", Min: 2},
+				Limit:  10,
+			},
+		},
+		{
+			name:   "optional parameters left out",
+			path:   "/query?limit=10",
+			params: operation.GetQueryParameters{Limit: 10},
+		},
+		{
+			name:    "header and cookie",
+			path:    "/header",
+			headers: http.Header{"X-Trace": {"x,y"}},
+			cookie:  "s",
+			params:  operation.GetHeaderParameters{XTrace: []string{"x", "y"}, Session: "s"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", c.path, nil)
+			for name, values := range c.headers {
+				req.Header[name] = values
+			}
+			if len(c.cookie) > 0 {
+				req.AddCookie(&http.Cookie{Name: "session", Value: c.cookie})
+			}
+
+			s := &server{}
+			res := httptest.NewRecorder()
+			NewRouter(s).ServeHTTP(res, req)
+			require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+			assert.Equal(t, c.params, s.params)
+		})
+	}
+}
+
+func TestParameterErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		path string
+		// the name and location of each parameter that failed
+		errors [][2]string
+	}{
+		{name: "not a number", path: "/label/.1,x", errors: [][2]string{{"ids", "path"}}},
+		{name: "missing label prefix", path: "/label/1,2", errors: [][2]string{{"ids", "path"}}},
+		{name: "unknown matrix parameter", path: "/matrix/;row=1;z=2", errors: [][2]string{{"point", "path"}}},
+		{name: "missing required", path: "/query", errors: [][2]string{{"limit", "query"}}},
+		{name: "every failure", path: "/query?ids=x&limit=y", errors: [][2]string{{"ids", "query"}, {"limit", "query"}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var bindErr error
+			router := NewRouter(&server{}, WithErrorMapper(func(ctx context.Context, err error) operation.Responder {
+				bindErr = err
+				return operation.DefaultErrorMapper(ctx, err)
+			}))
+
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, httptest.NewRequest("GET", c.path, nil))
+			assert.Equal(t, http.StatusBadRequest, res.Code)
+
+			var errs ParameterErrors
+			require.True(t, errors.As(bindErr, &errs), "%v", bindErr)
+			failed := [][2]string{}
+			for _, err := range errs {
+				failed = append(failed, [2]string{err.Name, err.In})
+			}
+			assert.Equal(t, c.errors, failed)
+		})
+	}
+}
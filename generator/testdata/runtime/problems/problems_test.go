@@ -0,0 +1,198 @@
+package generated
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/component"
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+// server fails getPet in a different way for each id.
+type server struct{}
+
+func (server) GetPet(ctx context.Context, params operation.GetPetParameters) (operation.Responder, error) {
+	switch params.Id {
+	case 1:
+		return nil, &operation.HTTPError{StatusCode: http.StatusConflict, Err: errors.New("pet 1 is busy")}
+	case 2:
+		problem := operation.NewProblem(http.StatusTeapot, "pet 2 is a teapot")
+		problem.Type = "https://example.com/problems/teapot"
+		problem.Extensions = map[string]interface{}{"spout": "short", "status": 1}
+		return nil, fmt.Errorf("wrapped: %w", problem)
+	case 3:
+		return nil, errors.New("database password is hunter2")
+	case 4:
+		panic("pet 4 exploded")
+	case 5:
+		return operation.NewProblem(http.StatusGone, "pet 5 is gone"), nil
+	}
+	return operation.NewGetPet200NegotiatedResponse(component.Pet{Id: params.Id}), nil
+}
+
+func (server) AddPet(ctx context.Context, params operation.AddPetParameters, body component.Pet) (operation.Responder, error) {
+	return operation.StatusCodeResponder(http.StatusNoContent), nil
+}
+
+// problemOf decodes a problem+json response.
+func problemOf(t *testing.T, res *httptest.ResponseRecorder) operation.Problem {
+	require.Equal(t, operation.ProblemContentType, res.Header().Get("Content-Type"), res.Body.String())
+	var problem operation.Problem
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &problem))
+	return problem
+}
+
+func TestProblems(t *testing.T) {
+	cases := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		accept      string
+		body        string
+		status      int
+		detail      string
+		errors      []operation.FieldError
+	}{
+		{name: "unknown path", method: "GET", path: "/nowhere", status: http.StatusNotFound, detail: "no operation matches /nowhere"},
+		{name: "unknown method", method: "DELETE", path: "/pets", status: http.StatusMethodNotAllowed, detail: "DELETE is not allowed on /pets"},
+		{
+			name:        "unsupported content type",
+			method:      "POST",
+			path:        "/pets",
+			contentType: "text/plain",
+			body:        "rex",
+			status:      http.StatusUnsupportedMediaType,
+			detail:      `content type "text/plain" is not supported`,
+		},
+		{
+			name:        "body of the wrong type",
+			method:      "POST",
+			path:        "/pets",
+			contentType: "application/json",
+			body:        `{"id": "one", "name": "rex"}`,
+			status:      http.StatusBadRequest,
+			detail:      "request body of addPet does not match the spec: /id: expected integer, got string",
+			errors:      []operation.FieldError{{Pointer: "/id", Detail: "expected integer, got string"}},
+		},
+		{
+			name:        "body breaking its schema",
+			method:      "POST",
+			path:        "/pets",
+			contentType: "application/json",
+			body:        `{"id": 1, "name": "rexrexrexrex"}`,
+			status:      http.StatusBadRequest,
+			detail:      "request body of addPet does not match the spec: /name: is longer than 8 characters",
+			errors:      []operation.FieldError{{Pointer: "/name", Detail: "is longer than 8 characters"}},
+		},
+		{
+			name:        "body missing a required property",
+			method:      "POST",
+			path:        "/pets",
+			contentType: "application/json",
+			body:        `{"id": 1}`,
+			status:      http.StatusBadRequest,
+			detail:      "request body of addPet does not match the spec: required property name is missing",
+			errors:      []operation.FieldError{{Pointer: "", Detail: "required property name is missing"}},
+		},
+		{
+			name:        "malformed body",
+			method:      "POST",
+			path:        "/pets",
+			contentType: "application/json",
+			body:        `{`,
+			status:      http.StatusBadRequest,
+			detail:      "invalid request body: unexpected end of JSON input",
+		},
+		{
+			name:   "invalid parameter",
+			method: "GET",
+			path:   "/pets/x",
+			status: http.StatusBadRequest,
+			detail: `path parameter "id": "x" is not a valid int64`,
+			errors: []operation.FieldError{{Name: "id", In: "path", Detail: `"x" is not a valid int64`}},
+		},
+		{
+			name:   "not acceptable",
+			method: "GET",
+			path:   "/pets/6",
+			accept: "text/html",
+			status: http.StatusNotAcceptable,
+			detail: "none of the response's content types match Accept: text/html",
+		},
+		{name: "HTTPError", method: "GET", path: "/pets/1", status: http.StatusConflict, detail: "pet 1 is busy"},
+		{name: "other error", method: "GET", path: "/pets/3", status: http.StatusInternalServerError},
+		{name: "panic", method: "GET", path: "/pets/4", status: http.StatusInternalServerError},
+		{name: "problem as a responder", method: "GET", path: "/pets/5", status: http.StatusGone, detail: "pet 5 is gone"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+			if len(c.contentType) > 0 {
+				req.Header.Set("Content-Type", c.contentType)
+			}
+			if len(c.accept) > 0 {
+				req.Header.Set("Accept", c.accept)
+			}
+
+			res := httptest.NewRecorder()
+			NewRouter(server{}).ServeHTTP(res, req)
+			require.Equal(t, c.status, res.Code, res.Body.String())
+			assert.Equal(t, operation.Problem{
+				Title:  http.StatusText(c.status),
+				Status: c.status,
+				Detail: c.detail,
+				Errors: c.errors,
+			}, problemOf(t, res))
+			if c.status == http.StatusMethodNotAllowed {
+				assert.Equal(t, "POST", res.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+func TestProblemExtensions(t *testing.T) {
+	res := httptest.NewRecorder()
+	NewRouter(server{}).ServeHTTP(res, httptest.NewRequest("GET", "/pets/2", nil))
+	require.Equal(t, http.StatusTeapot, res.Code)
+	assert.Equal(t, operation.ProblemContentType, res.Header().Get("Content-Type"))
+	// the standard members win over extensions of the same name
+	assert.JSONEq(t, `{
+		"type": "https://example.com/problems/teapot",
+		"title": "I'm a teapot",
+		"status": 418,
+		"detail": "pet 2 is a teapot",
+		"spout": "short"
+	}`, res.Body.String())
+}
+
+func TestProblemErrorMapper(t *testing.T) {
+	router := NewRouter(server{}, WithErrorMapper(func(ctx context.Context, err error) operation.Responder {
+		problem := operation.ProblemFromError(err)
+		problem.Type = fmt.Sprintf("https://example.com/problems/%d", problem.Status)
+		return problem
+	}))
+
+	// the router's own errors go through the mapper too
+	for _, path := range []string{"/pets/1", "/nowhere"} {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest("GET", path, nil))
+		problem := problemOf(t, res)
+		assert.Equal(t, fmt.Sprintf("https://example.com/problems/%d", res.Code), problem.Type)
+	}
+}
+
+func TestProblemFromError(t *testing.T) {
+	assert.Equal(t, "Conflict: busy", operation.ProblemFromError(&operation.HTTPError{StatusCode: http.StatusConflict, Err: errors.New("busy")}).Error())
+	assert.Equal(t, "Internal Server Error", operation.ProblemFromError(errors.New("secret")).Error())
+}
@@ -0,0 +1,116 @@
+package generated
+
+// The tests in testdata/runtime are copied into the package generated from
+// the spec.yaml next to them by TestGeneratedRuntime, which rewrites the
+// imports below to where it is generated.
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/component"
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+// server implements addPet, returning err if it is set.
+type server struct {
+	Unimplemented
+	err error
+	// names are the names of the pets that were added
+	names []string
+}
+
+func (s *server) AddPet(ctx context.Context, params operation.AddPetParameters, body component.Pet) (operation.Responder, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.names = append(s.names, body.Name)
+	return operation.StatusCodeResponder(http.StatusCreated), nil
+}
+
+func (s *server) AddPet_VndPets(ctx context.Context, params operation.AddPetParameters, body []component.Pet) (operation.Responder, error) {
+	for _, pet := range body {
+		s.names = append(s.names, pet.Name)
+	}
+	return operation.StatusCodeResponder(http.StatusCreated), nil
+}
+
+// serve sends req to handler.
+func serve(handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
+
+func TestUnimplemented(t *testing.T) {
+	res := serve(NewRouter(&server{}), httptest.NewRequest("GET", "/pets", nil))
+	assert.Equal(t, http.StatusNotImplemented, res.Code)
+}
+
+func TestRequestMediaTypes(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		names       []string
+	}{
+		{name: "json", contentType: "application/json", body: `{"name": "rex"}`, status: http.StatusCreated, names: []string{"rex"}},
+		{name: "parameters", contentType: "Application/JSON; charset=utf-8", body: `{"name": "rex"}`, status: http.StatusCreated, names: []string{"rex"}},
+		{name: "vendor type", contentType: "application/vnd.pets+json", body: `[{"name": "rex"}, {"name": "tom"}]`, status: http.StatusCreated, names: []string{"rex", "tom"}},
+		{name: "invalid body", contentType: "application/json", body: `[`, status: http.StatusBadRequest},
+		{name: "unsupported", contentType: "text/plain", body: "rex", status: http.StatusUnsupportedMediaType},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &server{}
+			req := httptest.NewRequest("POST", "/pets", strings.NewReader(c.body))
+			req.Header.Set("Content-Type", c.contentType)
+
+			res := serve(NewRouter(s), req)
+			assert.Equal(t, c.status, res.Code)
+			assert.Equal(t, c.names, s.names)
+		})
+	}
+}
+
+func TestErrorMapper(t *testing.T) {
+	errTaken := errors.New("taken")
+	cases := []struct {
+		name   string
+		err    error
+		opts   []RouterOption
+		status int
+	}{
+		{name: "http error", err: &operation.HTTPError{StatusCode: http.StatusConflict}, status: http.StatusConflict},
+		{name: "other error", err: errTaken, status: http.StatusInternalServerError},
+		{
+			name: "custom mapper",
+			err:  errTaken,
+			opts: []RouterOption{WithErrorMapper(func(ctx context.Context, err error) operation.Responder {
+				if errors.Is(err, errTaken) {
+					return operation.StatusCodeResponder(http.StatusConflict)
+				}
+				return operation.DefaultErrorMapper(ctx, err)
+			})},
+			status: http.StatusConflict,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/pets", strings.NewReader(`{"name": "rex"}`))
+			req.Header.Set("Content-Type", "application/json")
+
+			res := serve(NewRouter(&server{err: c.err}, c.opts...), req)
+			assert.Equal(t, c.status, res.Code)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package generated
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+// text is a text/plain response.
+type text string
+
+func (t text) WriteResponse(writer http.ResponseWriter) {
+	writer.Header().Set("Content-Type", "text/plain")
+	writer.WriteHeader(http.StatusOK)
+	writer.Write([]byte(t))
+}
+
+// server answers every operation with the principals of the request, as
+// scheme=principal pairs.
+type server struct{}
+
+func (server) GetSecure(ctx context.Context, params operation.GetSecureParameters) (operation.Responder, error) {
+	return principals(ctx), nil
+}
+
+func (server) GetDefault(ctx context.Context, params operation.GetDefaultParameters) (operation.Responder, error) {
+	return principals(ctx), nil
+}
+
+func (server) GetOAuth(ctx context.Context, params operation.GetOAuthParameters) (operation.Responder, error) {
+	return principals(ctx), nil
+}
+
+func (server) GetPublic(ctx context.Context, params operation.GetPublicParameters) (operation.Responder, error) {
+	return principals(ctx), nil
+}
+
+func principals(ctx context.Context) operation.Responder {
+	pairs := []string{}
+	for scheme, principal := range PrincipalsFromContext(ctx) {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", scheme, principal))
+	}
+	sort.Strings(pairs)
+	return text(strings.Join(pairs, ","))
+}
+
+// secret accepts the credentials with the value "secret", returning the
+// scopes it was asked for as the principal, and rejects "forbidden" as
+// lacking permission.
+func secret(value func(creds Credentials) string) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, creds Credentials, scopes []string) (interface{}, error) {
+		switch value(creds) {
+		case "secret":
+			return strings.Join(append([]string{creds.Scheme}, scopes...), " "), nil
+		case "forbidden":
+			return nil, fmt.Errorf("%s lacks %v: %w", creds.Scheme, scopes, ErrForbidden)
+		}
+		return nil, errors.New("unknown credentials")
+	})
+}
+
+// problemOf decodes a problem+json response.
+func problemOf(t *testing.T, res *httptest.ResponseRecorder) operation.Problem {
+	require.Equal(t, operation.ProblemContentType, res.Header().Get("Content-Type"), res.Body.String())
+	var problem operation.Problem
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &problem))
+	return problem
+}
+
+func TestSecurity(t *testing.T) {
+	apiKey := func(creds Credentials) string { return creds.APIKey }
+	token := func(creds Credentials) string { return creds.Token }
+	router := NewRouter(server{},
+		WithAuthenticator("key", secret(apiKey)),
+		WithAuthenticator("query", secret(apiKey)),
+		WithAuthenticator("cookie", secret(apiKey)),
+		WithAuthenticator("token", secret(token)),
+		WithAuthenticator("oauth", secret(token)),
+		WithAuthenticator("basic", secret(func(creds Credentials) string {
+			if creds.Username != "user" {
+				return ""
+			}
+			return creds.Password
+		})),
+	)
+
+	cases := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		basic   string
+		cookie  string
+		status  int
+		// the principals, or the challenges of a 401
+		principals string
+		challenges []string
+	}{
+		{
+			name:       "all schemes of a requirement",
+			path:       "/secure",
+			headers:    map[string]string{"X-API-Key": "secret", "Authorization": "Bearer secret"},
+			status:     http.StatusOK,
+			principals: "key=key,token=token read",
+		},
+		{
+			name:       "second requirement",
+			path:       "/secure",
+			basic:      "secret",
+			status:     http.StatusOK,
+			principals: "basic=basic",
+		},
+		{
+			name:       "only some schemes of a requirement",
+			path:       "/secure",
+			headers:    map[string]string{"X-API-Key": "secret"},
+			status:     http.StatusUnauthorized,
+			challenges: []string{"Bearer", `Basic realm="api"`},
+		},
+		{
+			name:       "rejected credentials",
+			path:       "/secure",
+			headers:    map[string]string{"X-API-Key": "wrong", "Authorization": "Bearer secret"},
+			status:     http.StatusUnauthorized,
+			challenges: []string{"Bearer", `Basic realm="api"`},
+		},
+		{
+			name:       "wrong password",
+			path:       "/secure",
+			basic:      "wrong",
+			status:     http.StatusUnauthorized,
+			challenges: []string{"Bearer", `Basic realm="api"`},
+		},
+		{
+			name:       "no credentials",
+			path:       "/secure",
+			status:     http.StatusUnauthorized,
+			challenges: []string{"Bearer", `Basic realm="api"`},
+		},
+		{
+			name:    "forbidden",
+			path:    "/secure",
+			headers: map[string]string{"X-API-Key": "secret", "Authorization": "Bearer forbidden"},
+			status:  http.StatusForbidden,
+		},
+		{
+			name:       "document requirement",
+			path:       "/default?api_key=secret",
+			status:     http.StatusOK,
+			principals: "query=query",
+		},
+		{
+			name:   "document requirement without credentials",
+			path:   "/default",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:       "oauth2 scopes",
+			path:       "/oauth",
+			headers:    map[string]string{"Authorization": "Bearer secret"},
+			status:     http.StatusOK,
+			principals: "oauth=oauth pets:read",
+		},
+		{
+			name:       "cookie",
+			path:       "/oauth",
+			cookie:     "secret",
+			status:     http.StatusOK,
+			principals: "cookie=cookie",
+		},
+		{
+			name:       "oauth2 challenge",
+			path:       "/oauth",
+			status:     http.StatusUnauthorized,
+			challenges: []string{"Bearer"},
+		},
+		{
+			name:   "no security",
+			path:   "/public",
+			status: http.StatusOK,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", c.path, nil)
+			for name, value := range c.headers {
+				req.Header.Set(name, value)
+			}
+			if len(c.basic) > 0 {
+				req.SetBasicAuth("user", c.basic)
+			}
+			if len(c.cookie) > 0 {
+				req.AddCookie(&http.Cookie{Name: "session", Value: c.cookie})
+			}
+
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			require.Equal(t, c.status, res.Code, res.Body.String())
+			if c.status == http.StatusOK {
+				assert.Equal(t, c.principals, res.Body.String())
+			} else {
+				assert.Equal(t, c.status, problemOf(t, res).Status)
+			}
+			assert.Equal(t, c.challenges, res.Header().Values("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestMissingAuthenticator(t *testing.T) {
+	// without an Authenticator for token, only basic credentials get in
+	router := NewRouter(server{},
+		WithAuthenticator("key", AuthenticatorFunc(func(ctx context.Context, creds Credentials, scopes []string) (interface{}, error) {
+			return "anyone", nil
+		})),
+	)
+
+	req := httptest.NewRequest("GET", "/secure", nil)
+	req.Header.Set("X-API-Key", "secret")
+	req.Header.Set("Authorization", "Bearer secret")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Equal(t, http.StatusUnauthorized, problemOf(t, res).Status)
+}
+
+func TestPrincipalFromContext(t *testing.T) {
+	ctx := context.Background()
+	_, ok := PrincipalFromContext(ctx, "key")
+	assert.False(t, ok)
+	assert.Empty(t, PrincipalsFromContext(ctx))
+}
@@ -0,0 +1,166 @@
+package generated
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type contextKey struct{}
+
+func TestNewServer(t *testing.T) {
+	handler := NewRouter(Unimplemented{})
+	config := &tls.Config{}
+	base := context.WithValue(context.Background(), contextKey{}, "base")
+
+	s := NewServer("127.0.0.1:8080",
+		WithHandler(handler),
+		WithReadTimeout(time.Second),
+		WithReadHeaderTimeout(2*time.Second),
+		WithWriteTimeout(3*time.Second),
+		WithIdleTimeout(4*time.Second),
+		WithMaxHeaderBytes(1024),
+		WithTLSConfig(config),
+		WithBaseContext(base),
+		WithShutdownTimeout(5*time.Second),
+	)
+	assert.Equal(t, "127.0.0.1:8080", s.Addr)
+	assert.Equal(t, handler, s.Handler)
+	assert.Equal(t, time.Second, s.ReadTimeout)
+	assert.Equal(t, 2*time.Second, s.ReadHeaderTimeout)
+	assert.Equal(t, 3*time.Second, s.WriteTimeout)
+	assert.Equal(t, 4*time.Second, s.IdleTimeout)
+	assert.Equal(t, 1024, s.MaxHeaderBytes)
+	assert.Same(t, config, s.TLSConfig)
+	require.NotNil(t, s.BaseContext)
+	assert.Equal(t, "base", s.BaseContext(nil).Value(contextKey{}))
+	assert.Equal(t, 5*time.Second, s.ShutdownTimeout)
+
+	defaults := NewServer("127.0.0.1:8080")
+	assert.Equal(t, 120*time.Second, defaults.ReadTimeout)
+	assert.Equal(t, 10*time.Second, defaults.ReadHeaderTimeout)
+	assert.Equal(t, 120*time.Second, defaults.WriteTimeout)
+	assert.Equal(t, 120*time.Second, defaults.IdleTimeout)
+	assert.Equal(t, 30*time.Second, defaults.ShutdownTimeout)
+
+	// without a handler nothing registered on http.DefaultServeMux is served
+	http.HandleFunc("/registered", func(res http.ResponseWriter, req *http.Request) {})
+	res := httptest.NewRecorder()
+	defaults.Handler.ServeHTTP(res, httptest.NewRequest("GET", "/registered", nil))
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+// freeAddress returns a local address that nothing is listening on.
+func freeAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// slowHandler signals started when a request arrives, then answers it once
+// release is closed.
+type slowHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *slowHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	close(h.started)
+	<-h.release
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// runWithRequest starts s, sends it a request and cancels the context of Run
+// once the request is being handled. It returns the results of Run and of
+// the request.
+func runWithRequest(t *testing.T, s *Server, h *slowHandler) (chan error, chan *http.Response) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Run(ctx) }()
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		for {
+			res, err := http.Get("http://" + s.Addr + "/health")
+			if err == nil {
+				res.Body.Close()
+				responses <- res
+				return
+			}
+			select {
+			case <-h.started:
+				// the request arrived, but its connection was closed
+				return
+			case <-time.After(10 * time.Millisecond):
+				// not listening yet
+			}
+		}
+	}()
+
+	select {
+	case <-h.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request never arrived")
+	}
+	cancel()
+	return stopped, responses
+}
+
+func TestRunWaitsForRequests(t *testing.T) {
+	h := &slowHandler{started: make(chan struct{}), release: make(chan struct{})}
+	s := NewServer(freeAddress(t), WithHandler(h), WithShutdownTimeout(5*time.Second))
+
+	stopped, responses := runWithRequest(t, s, h)
+	select {
+	case err := <-stopped:
+		t.Fatalf("Run returned with a request in flight: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(h.release)
+	select {
+	case res := <-responses:
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request didn't finish")
+	}
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return")
+	}
+}
+
+func TestRunShutdownTimeout(t *testing.T) {
+	h := &slowHandler{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(h.release)
+	s := NewServer(freeAddress(t), WithHandler(h), WithShutdownTimeout(50*time.Millisecond))
+
+	stopped, _ := runWithRequest(t, s, h)
+	select {
+	case err := <-stopped:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't give up on the request")
+	}
+}
+
+func TestRunListenError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	s := NewServer(l.Addr().String(), WithHandler(http.NotFoundHandler()))
+	assert.Error(t, s.Run(context.Background()))
+}
@@ -0,0 +1,100 @@
+package generated
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+func TestBundledSpec(t *testing.T) {
+	var doc struct {
+		Info struct {
+			Title string `json:"title" yaml:"title"`
+		} `json:"info" yaml:"info"`
+		Paths map[string]map[string]struct {
+			Responses map[string]struct {
+				Content map[string]struct {
+					Schema map[string]interface{} `json:"schema" yaml:"schema"`
+				} `json:"content" yaml:"content"`
+			} `json:"responses" yaml:"responses"`
+		} `json:"paths" yaml:"paths"`
+	}
+
+	for name, unmarshal := range map[string]func() error{
+		"json": func() error { return json.Unmarshal(SpecJSON(), &doc) },
+		"yaml": func() error { return yaml.Unmarshal(SpecYAML(), &doc) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, unmarshal())
+			assert.Equal(t, "spec", doc.Info.Title)
+
+			// the $ref is replaced by the schema it points at
+			schema := doc.Paths["/pets"]["get"].Responses["200"].Content["application/json"].Schema
+			assert.NotContains(t, schema, "$ref")
+			assert.Equal(t, "object", schema["type"])
+			assert.Contains(t, schema["properties"], "family")
+		})
+	}
+
+	// the document keeps the order it was written in
+	assert.Less(t, strings.Index(string(SpecJSON()), `"paths"`), strings.Index(string(SpecJSON()), `"components"`))
+}
+
+func TestSpecRoutes(t *testing.T) {
+	moved := WithSpecPaths("/api/openapi.json", "", "/api/docs")
+	cases := []struct {
+		name string
+		opts []RouterOption
+		path string
+		// the content type and body served, empty if the route is missing
+		contentType string
+		body        string
+	}{
+		{name: "json", path: "/openapi.json", contentType: "application/json", body: string(SpecJSON())},
+		{name: "yaml", path: "/openapi.yaml", contentType: "application/yaml", body: string(SpecYAML())},
+		{name: "docs", path: "/docs", contentType: "text/html; charset=utf-8"},
+		{name: "moved", opts: []RouterOption{moved}, path: "/api/openapi.json", contentType: "application/json", body: string(SpecJSON())},
+		{name: "moved docs", opts: []RouterOption{moved}, path: "/api/docs", contentType: "text/html; charset=utf-8"},
+		{name: "moved away", opts: []RouterOption{moved}, path: "/openapi.json"},
+		{name: "disabled", opts: []RouterOption{moved}, path: "/openapi.yaml"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			NewRouter(Unimplemented{}, c.opts...).ServeHTTP(res, httptest.NewRequest("GET", c.path, nil))
+			if len(c.contentType) == 0 {
+				assert.Equal(t, http.StatusNotFound, res.Code)
+				assert.Equal(t, operation.ProblemContentType, res.Header().Get("Content-Type"))
+				return
+			}
+			require.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, c.contentType, res.Header().Get("Content-Type"))
+			if len(c.body) > 0 {
+				assert.Equal(t, c.body, res.Body.String())
+			}
+		})
+	}
+}
+
+func TestDocsPage(t *testing.T) {
+	res := httptest.NewRecorder()
+	NewRouter(Unimplemented{}).ServeHTTP(res, httptest.NewRequest("GET", "/docs", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), "<code>listPets</code>")
+	assert.Contains(t, res.Body.String(), "List every pet")
+
+	// HEAD and conditional requests are answered by http.ServeContent
+	res = httptest.NewRecorder()
+	NewRouter(Unimplemented{}).ServeHTTP(res, httptest.NewRequest("HEAD", "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Empty(t, res.Body.String())
+}
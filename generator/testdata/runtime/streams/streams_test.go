@@ -0,0 +1,195 @@
+package generated
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+// server answers getFile with file, getNote with a fixed note and getEvents
+// with events.
+type server struct {
+	file   func() operation.Responder
+	events chan operation.Event
+}
+
+func (s server) GetFile(ctx context.Context, params operation.GetFileParameters) (operation.Responder, error) {
+	return s.file(), nil
+}
+
+func (s server) GetNote(ctx context.Context, params operation.GetNoteParameters) (operation.Responder, error) {
+	return operation.NewGetNote200Response_TextPlain("remember the milk"), nil
+}
+
+func (s server) GetEvents(ctx context.Context, params operation.GetEventsParameters) (operation.Responder, error) {
+	return operation.NewGetEvents200Response_TextEventStream(s.events), nil
+}
+
+// closeRecorder is a reader that records whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func serve(handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
+
+func TestBinary(t *testing.T) {
+	const content = "0123456789"
+
+	cases := []struct {
+		name   string
+		body   func() io.Reader
+		length int64
+		rng    string
+		status int
+		header http.Header
+		want   string
+	}{
+		{
+			name:   "seeker",
+			body:   func() io.Reader { return strings.NewReader(content) },
+			status: http.StatusOK,
+			header: http.Header{"Content-Length": {"10"}, "Accept-Ranges": {"bytes"}},
+			want:   content,
+		},
+		{
+			name:   "range",
+			body:   func() io.Reader { return strings.NewReader(content) },
+			rng:    "bytes=2-4",
+			status: http.StatusPartialContent,
+			header: http.Header{"Content-Range": {"bytes 2-4/10"}, "Content-Length": {"3"}},
+			want:   "234",
+		},
+		{
+			name:   "unsatisfiable range",
+			body:   func() io.Reader { return strings.NewReader(content) },
+			rng:    "bytes=20-30",
+			status: http.StatusRequestedRangeNotSatisfiable,
+			header: http.Header{"Content-Range": {"bytes */10"}},
+		},
+		{
+			name:   "reader with a length",
+			body:   func() io.Reader { return io.LimitReader(strings.NewReader(content), 10) },
+			length: 10,
+			rng:    "bytes=2-4",
+			status: http.StatusOK,
+			header: http.Header{"Content-Length": {"10"}},
+			want:   content,
+		},
+		{
+			name:   "reader",
+			body:   func() io.Reader { return io.LimitReader(strings.NewReader(content), 10) },
+			status: http.StatusOK,
+			header: http.Header{},
+			want:   content,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			router := NewRouter(server{file: func() operation.Responder {
+				r := operation.NewGetFile200Response_OctetStream(c.body())
+				r.ContentLength = c.length
+				return r
+			}})
+
+			req := httptest.NewRequest("GET", "/file", nil)
+			if len(c.rng) > 0 {
+				req.Header.Set("Range", c.rng)
+			}
+			res := serve(router, req)
+			require.Equal(t, c.status, res.Code, res.Body.String())
+			for name := range c.header {
+				assert.Equal(t, c.header[name], res.Header().Values(name), name)
+			}
+			if c.status != http.StatusRequestedRangeNotSatisfiable {
+				assert.Equal(t, "application/octet-stream", res.Header().Get("Content-Type"))
+				assert.Equal(t, c.want, res.Body.String())
+			}
+		})
+	}
+}
+
+func TestBinaryClosed(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader("data")}
+	router := NewRouter(server{file: func() operation.Responder {
+		return operation.NewGetFile200Response_OctetStream(body)
+	}})
+
+	res := serve(router, httptest.NewRequest("GET", "/file", nil))
+	assert.Equal(t, "data", res.Body.String())
+	assert.True(t, body.closed)
+}
+
+func TestText(t *testing.T) {
+	res := serve(NewRouter(server{}), httptest.NewRequest("GET", "/note", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "text/plain", res.Header().Get("Content-Type"))
+	assert.Equal(t, "remember the milk", res.Body.String())
+}
+
+func TestEvents(t *testing.T) {
+	events := make(chan operation.Event, 3)
+	events <- operation.Event{ID: "1", Event: "tick", Data: "a\nb"}
+	events <- operation.Event{Data: "c", Retry: 2 * time.Second}
+	close(events)
+
+	res := serve(NewRouter(server{events: events}), httptest.NewRequest("GET", "/events", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "text/event-stream", res.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", res.Header().Get("Cache-Control"))
+	assert.True(t, res.Flushed)
+	assert.Equal(t, "id: 1\nevent: tick\ndata: a\ndata: b\n\nretry: 2000\ndata: c\n\n", res.Body.String())
+}
+
+func TestEventLineBreaks(t *testing.T) {
+	events := make(chan operation.Event, 1)
+	// a line break in the id or name would start a field of its own
+	events <- operation.Event{ID: "1\r\n2", Event: "tick\ndata: forged", Data: "a\r\nb\rc\nd"}
+	close(events)
+
+	res := serve(NewRouter(server{events: events}), httptest.NewRequest("GET", "/events", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "id: 12\nevent: tickdata: forged\ndata: a\ndata: b\ndata: c\ndata: d\n\n", res.Body.String())
+}
+
+func TestEventsClientGone(t *testing.T) {
+	// the channel is never closed; the stream ends with the request
+	events := make(chan operation.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/events", nil).WithContext(ctx)
+	res := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		NewRouter(server{events: events}).ServeHTTP(res, req)
+		close(done)
+	}()
+	events <- operation.Event{Data: "a"}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream didn't end with the request")
+	}
+	assert.Equal(t, "data: a\n\n", res.Body.String())
+}
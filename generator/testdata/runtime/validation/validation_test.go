@@ -0,0 +1,257 @@
+package generated
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/component"
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+// server answers addPet with response, getPet with a valid pet and getFile
+// with a seekable file.
+type server struct {
+	response operation.Responder
+}
+
+func (s server) AddPet(ctx context.Context, params operation.AddPetParameters, body component.Pet) (operation.Responder, error) {
+	return s.response, nil
+}
+
+func (s server) GetPet(ctx context.Context, params operation.GetPetParameters) (operation.Responder, error) {
+	return operation.NewGetPet200NegotiatedResponse(component.Pet{Id: params.Id, Name: "rex", Kind: "dog", Tags: []string{}}), nil
+}
+
+func (s server) GetFile(ctx context.Context, params operation.GetFileParameters) (operation.Responder, error) {
+	return etagged{operation.NewGetFile200Response_OctetStream(strings.NewReader("0123456789"))}, nil
+}
+
+// etagged sets an ETag so that http.ServeContent answers If-None-Match.
+type etagged struct {
+	*operation.GetFile200Response_OctetStream
+}
+
+func (e etagged) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("ETag", `"v1"`)
+	e.GetFile200Response_OctetStream.ServeHTTP(res, req)
+}
+
+// raw writes a response of any status, content type and body, which the
+// generated types can't do when it is invalid.
+type raw struct {
+	status      int
+	contentType string
+	body        string
+}
+
+func (r raw) WriteResponse(writer http.ResponseWriter) {
+	if len(r.contentType) > 0 {
+		writer.Header().Set("Content-Type", r.contentType)
+	}
+	writer.Header().Set("Location", "/pets/1")
+	writer.WriteHeader(r.status)
+	writer.Write([]byte(r.body))
+}
+
+// problemOf decodes a problem+json response.
+func problemOf(t *testing.T, res *httptest.ResponseRecorder) operation.Problem {
+	require.Equal(t, operation.ProblemContentType, res.Header().Get("Content-Type"), res.Body.String())
+	var problem operation.Problem
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &problem))
+	return problem
+}
+
+func addPet(router http.Handler) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/pets", strings.NewReader(`{"id": 1, "name": "rex"}`))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func TestResponseValidation(t *testing.T) {
+	valid := component.Pet{Id: 1, Name: "rex", Kind: "dog", Tags: []string{}}
+
+	cases := []struct {
+		name     string
+		response operation.Responder
+		status   int
+		// the problems found, if the response is invalid
+		problems []operation.FieldError
+	}{
+		{
+			name:     "valid",
+			response: operation.NewAddPet201Response("/pets/1", valid),
+			status:   http.StatusCreated,
+		},
+		{
+			name:     "status range",
+			response: raw{status: http.StatusConflict, contentType: "application/json", body: `{"message": "taken"}`},
+			status:   http.StatusConflict,
+		},
+		{
+			name:     "missing header",
+			response: operation.JsonResponder(http.StatusCreated, "application/json", valid),
+			problems: []operation.FieldError{{Name: "Location", In: "header", Detail: "is required"}},
+		},
+		{
+			name:     "invalid body",
+			response: operation.NewAddPet201Response("/pets/1", component.Pet{Id: 1, Name: "rexrexrexrex", Kind: "cow", Tags: []string{"a", "b", "c"}}),
+			problems: []operation.FieldError{
+				{Pointer: "/kind", Detail: `"cow" is not one of the allowed values`},
+				{Pointer: "/name", Detail: "is longer than 8 characters"},
+				{Pointer: "/tags", Detail: "has 3 items, more than 2"},
+			},
+		},
+		{
+			name:     "missing property",
+			response: raw{status: http.StatusCreated, contentType: "application/json", body: `{"id": 1}`},
+			problems: []operation.FieldError{{Pointer: "", Detail: "required property name is missing"}},
+		},
+		{
+			name:     "wrong type",
+			response: raw{status: http.StatusCreated, contentType: "application/json", body: `{"id": "1", "name": "rex"}`},
+			problems: []operation.FieldError{{Pointer: "/id", Detail: "expected integer, got string"}},
+		},
+		{
+			name:     "not JSON",
+			response: raw{status: http.StatusCreated, contentType: "application/json", body: `{`},
+			problems: []operation.FieldError{{Detail: "body is not valid JSON: unexpected end of JSON input"}},
+		},
+		{
+			name:     "undeclared status code",
+			response: raw{status: http.StatusInternalServerError},
+			problems: []operation.FieldError{{Detail: "status code 500 is not declared"}},
+		},
+		{
+			name:     "undeclared content type",
+			response: raw{status: http.StatusCreated, contentType: "text/plain", body: "rex"},
+			problems: []operation.FieldError{{Detail: `content type "text/plain" is not declared`}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var problems []operation.FieldError
+			router := NewRouter(server{response: c.response}, WithResponseValidation(func(req *http.Request, err *ResponseValidationError) error {
+				assert.Equal(t, "addPet", err.Operation.OperationID)
+				problems = err.Problems
+				return FailResponseViolations(req, err)
+			}))
+
+			res := addPet(router)
+			assert.Equal(t, c.problems, problems)
+			if c.problems == nil {
+				assert.Equal(t, c.status, res.Code, res.Body.String())
+				return
+			}
+			require.Equal(t, http.StatusInternalServerError, res.Code)
+			assert.Empty(t, res.Header().Values("Location"))
+			assert.Equal(t, c.problems, problemOf(t, res).Errors)
+		})
+	}
+}
+
+func TestUncheckedResponses(t *testing.T) {
+	cases := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		header      http.Header
+		status      int
+	}{
+		{name: "malformed parameter", method: "GET", path: "/pets/one", header: http.Header{"X-Api-Key": {"secret"}}, status: http.StatusBadRequest},
+		{name: "malformed body", method: "POST", path: "/pets", contentType: "application/json", body: `{`, status: http.StatusBadRequest},
+		{name: "unsupported media type", method: "POST", path: "/pets", contentType: "text/plain", body: "rex", status: http.StatusUnsupportedMediaType},
+		{name: "unauthenticated", method: "GET", path: "/pets/1", status: http.StatusUnauthorized},
+		{name: "forbidden", method: "GET", path: "/pets/1", header: http.Header{"X-Api-Key": {"forbidden"}}, status: http.StatusForbidden},
+		{name: "not acceptable", method: "GET", path: "/pets/1", header: http.Header{"X-Api-Key": {"secret"}, "Accept": {"text/csv"}}, status: http.StatusNotAcceptable},
+		{name: "partial content", method: "GET", path: "/file", header: http.Header{"Range": {"bytes=2-4"}}, status: http.StatusPartialContent},
+		{name: "not modified", method: "GET", path: "/file", header: http.Header{"If-None-Match": {`"v1"`}}, status: http.StatusNotModified},
+		{name: "unsatisfiable range", method: "GET", path: "/file", header: http.Header{"Range": {"bytes=20-30"}}, status: http.StatusRequestedRangeNotSatisfiable},
+	}
+
+	authenticator := AuthenticatorFunc(func(ctx context.Context, creds Credentials, scopes []string) (interface{}, error) {
+		if creds.APIKey == "secret" {
+			return "rex", nil
+		}
+		if creds.APIKey == "forbidden" {
+			return nil, ErrForbidden
+		}
+		return nil, errors.New("unknown key")
+	})
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			router := NewRouter(server{},
+				WithAuthenticator("key", authenticator),
+				WithResponseValidation(func(req *http.Request, err *ResponseValidationError) error {
+					t.Errorf("unexpected violation: %v", err)
+					return FailResponseViolations(req, err)
+				}),
+			)
+
+			req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+			if len(c.contentType) > 0 {
+				req.Header.Set("Content-Type", c.contentType)
+			}
+			for name, values := range c.header {
+				req.Header[name] = values
+			}
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			assert.Equal(t, c.status, res.Code, res.Body.String())
+		})
+	}
+}
+
+func TestValidResponseUnchanged(t *testing.T) {
+	response := operation.NewAddPet201Response("/pets/1", component.Pet{Id: 1, Name: "rex", Kind: "cat", Tags: []string{}})
+	without := addPet(NewRouter(server{response: response}))
+	with := addPet(NewRouter(server{response: response}, WithResponseValidation(FailResponseViolations)))
+
+	assert.Equal(t, without.Code, with.Code)
+	assert.Equal(t, without.Header(), with.Header())
+	assert.Equal(t, without.Body.String(), with.Body.String())
+	var pet component.Pet
+	require.NoError(t, json.Unmarshal(with.Body.Bytes(), &pet))
+}
+
+func TestLogResponseViolations(t *testing.T) {
+	var logs bytes.Buffer
+	response := raw{status: http.StatusCreated, contentType: "application/json", body: `{"id": 1}`}
+	router := NewRouter(server{response: response}, WithResponseValidation(LogResponseViolations(log.New(&logs, "", 0))))
+
+	res := addPet(router)
+	// the response is let through as it is
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Equal(t, `{"id": 1}`, res.Body.String())
+	assert.Contains(t, logs.String(), "201 response from addPet does not match the spec")
+}
+
+func TestResponseValidationError(t *testing.T) {
+	err := &ResponseValidationError{
+		Operation:  Operations["addPet"],
+		StatusCode: http.StatusCreated,
+		Problems: []operation.FieldError{
+			{Pointer: "/name", Detail: "is too long"},
+			{Name: "Location", In: "header", Detail: "is required"},
+			{Detail: "status code 201 is not declared"},
+		},
+	}
+	assert.Equal(t, "201 response from addPet does not match the spec: /name: is too long; header Location: is required; status code 201 is not declared", err.Error())
+	assert.Equal(t, err.Problems, err.FieldErrors())
+	assert.True(t, errors.As(FailResponseViolations(nil, err), &err))
+}
@@ -0,0 +1,128 @@
+package generated
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/component"
+	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
+)
+
+var rex = component.Pet{Id: 1, Name: "rex", Tags: []string{"dog", "good"}}
+
+// assertRex checks that body holds rex as XML. The order of the child
+// elements follows the struct fields, which isn't fixed.
+func assertRex(t *testing.T, body string) {
+	assert.True(t, strings.HasPrefix(body, xml.Header+`<pet id="1">`), body)
+	assert.True(t, strings.HasSuffix(body, `</pet>`), body)
+	assert.Contains(t, body, `<name>rex</name>`)
+	assert.Contains(t, body, `<tags><tag>dog</tag><tag>good</tag></tags>`)
+}
+
+type server struct{}
+
+func (server) GetPet(ctx context.Context, params operation.GetPetParameters) (operation.Responder, error) {
+	return operation.NewGetPet200NegotiatedResponse(rex), nil
+}
+
+func (server) ListPets(ctx context.Context, params operation.ListPetsParameters) (operation.Responder, error) {
+	return operation.NewListPets200Response_Xml([]component.Pet{rex, {Id: 2, Name: "tom"}}), nil
+}
+
+// AddPet_Xml echoes the pet.
+func (server) AddPet_Xml(ctx context.Context, params operation.AddPetParameters, body component.Pet) (operation.Responder, error) {
+	return operation.NewAddPet201Response_Xml(body), nil
+}
+
+func (server) GetLabels(ctx context.Context, params operation.GetLabelsParameters) (operation.Responder, error) {
+	return operation.NewGetLabels200Response_Xml(component.Labels{Values: []string{"a", "b"}}), nil
+}
+
+func serve(req *http.Request) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	NewRouter(server{}).ServeHTTP(res, req)
+	return res
+}
+
+func TestXMLResponse(t *testing.T) {
+	res := serve(httptest.NewRequest("GET", "/list", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/xml", res.Header().Get("Content-Type"))
+	body := res.Body.String()
+	assert.True(t, strings.HasPrefix(body, xml.Header+`<pets><pet id="1">`), body)
+	assert.True(t, strings.HasSuffix(body, `</pet></pets>`), body)
+
+	var pets struct {
+		XMLName xml.Name        `xml:"pets"`
+		Pets    []component.Pet `xml:"pet"`
+	}
+	require.NoError(t, xml.Unmarshal(res.Body.Bytes(), &pets))
+	assert.Equal(t, []component.Pet{rex, {Id: 2, Name: "tom"}}, pets.Pets)
+}
+
+func TestXMLNamespace(t *testing.T) {
+	res := serve(httptest.NewRequest("GET", "/labels", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	// encoding/xml writes the namespace on the items of a wrapped array, and
+	// reads it from the wrapper
+	assert.Equal(t, xml.Header+`<labels><values><label xmlns="urn:labels">a</label><label xmlns="urn:labels">b</label></values></labels>`, res.Body.String())
+
+	var labels component.Labels
+	require.NoError(t, xml.Unmarshal([]byte(`<labels><values xmlns="urn:labels"><label>a</label><label>b</label></values></labels>`), &labels))
+	assert.Equal(t, []string{"a", "b"}, labels.Values)
+}
+
+func TestXMLRequest(t *testing.T) {
+	req := httptest.NewRequest("POST", "/pets", strings.NewReader(`<pet id="1"><name>rex</name><tags><tag>dog</tag><tag>good</tag></tags></pet>`))
+	req.Header.Set("Content-Type", "application/xml")
+	res := serve(req)
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	assertRex(t, res.Body.String())
+
+	req = httptest.NewRequest("POST", "/pets", strings.NewReader(`<pet><name>`))
+	req.Header.Set("Content-Type", "application/xml")
+	assert.Equal(t, http.StatusBadRequest, serve(req).Code)
+}
+
+func TestNegotiation(t *testing.T) {
+	cases := []struct {
+		accept      string
+		status      int
+		contentType string
+	}{
+		{accept: "", status: http.StatusOK, contentType: "application/json"},
+		{accept: "application/xml", status: http.StatusOK, contentType: "application/xml"},
+		{accept: "application/json;q=0.5, application/xml", status: http.StatusOK, contentType: "application/xml"},
+		{accept: "application/*", status: http.StatusOK, contentType: "application/json"},
+		{accept: "*/*;q=0.1, application/xml;q=0.2", status: http.StatusOK, contentType: "application/xml"},
+		{accept: "application/xml;q=0, */*", status: http.StatusOK, contentType: "application/json"},
+		{accept: "text/html", status: http.StatusNotAcceptable},
+	}
+
+	for _, c := range cases {
+		t.Run(c.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/pets", nil)
+			if len(c.accept) > 0 {
+				req.Header.Set("Accept", c.accept)
+			}
+			res := serve(req)
+			require.Equal(t, c.status, res.Code)
+			if c.status != http.StatusOK {
+				return
+			}
+			assert.Equal(t, c.contentType, res.Header().Get("Content-Type"))
+			if c.contentType == "application/xml" {
+				assertRex(t, res.Body.String())
+			} else {
+				assert.JSONEq(t, `{"id": 1, "name": "rex", "tags": ["dog", "good"]}`, res.Body.String())
+			}
+		})
+	}
+}
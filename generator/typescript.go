@@ -0,0 +1,317 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/parser"
+	"github.com/mllrjb/hackathon-go-openapi-v3/utils"
+)
+
+// typeScriptFiles are the templates of the TypeScript client, executed once
+// per spec with a TemplateData, like the Go support files.
+var typeScriptFiles = []supportFile{
+	{Template: "types.ts.tmpl", Output: "types.ts"},
+	{Template: "client.ts.tmpl", Output: "client.ts"},
+}
+
+// GenerateTypeScriptFiles writes the component schemas as TypeScript types,
+// and a fetch client with a method per handler, to config.OutputDir. The
+// templates get the same TemplateData as the Go server's, so the names and
+// order of everything match it.
+func GenerateTypeScriptFiles(walker parser.Walker, config Config) {
+	t, err := LoadTemplates(config.TemplateDir, templateFuncs())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	data := templateData(walker, config)
+	for _, f := range typeScriptFiles {
+		tmpl := t.Lookup(f.Template)
+		if tmpl == nil {
+			fmt.Printf("could not find %s template\n", f.Template)
+			os.Exit(1)
+		}
+
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, data)
+		if err != nil {
+			fmt.Printf("error processing %s: %v\n", f.Template, err)
+			os.Exit(1)
+		}
+
+		err = writeFile(fmt.Sprintf("%s/%s", config.OutputDir, f.Output), buf.Bytes())
+		if err != nil {
+			fmt.Printf("unable to write %s: %v\n", f.Output, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// tsString quotes s as a TypeScript string literal. JSON strings are valid
+// ones, and encoding/json escapes the line separators JavaScript doesn't
+// allow in them.
+func tsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// tsLiteral renders an enum value as a TypeScript literal type.
+func tsLiteral(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "unknown"
+	}
+	return string(b)
+}
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsKey is a property name as written in an interface or object literal:
+// quoted unless it is an identifier.
+func tsKey(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return tsString(name)
+}
+
+// tsDoc renders each non-empty paragraph as a JSDoc comment, indented by
+// indent, followed by a @deprecated tag if needed.
+func tsDoc(indent string, deprecated bool, paragraphs ...string) string {
+	var parts []string
+	for _, p := range paragraphs {
+		p = strings.TrimSpace(p)
+		if len(p) > 0 {
+			parts = append(parts, utils.WrapText(p, docWidth))
+		}
+	}
+	if deprecated {
+		parts = append(parts, "@deprecated marked as deprecated in the API specification.")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	lines := strings.Split(strings.Join(parts, "\n\n"), "\n")
+	var b strings.Builder
+	b.WriteString(indent + "/**\n")
+	for _, line := range lines {
+		line = strings.ReplaceAll(line, "*/", "*\\/")
+		b.WriteString(strings.TrimRight(indent+" * "+line, " ") + "\n")
+	}
+	b.WriteString(indent + " */\n")
+	return b.String()
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+// tsPrimitive is the type of a primitive schema: a union of its enum
+// values, if it has any.
+func tsPrimitive(gs *GenSchema) string {
+	if len(gs.Enum) > 0 {
+		literals := make([]string, len(gs.Enum))
+		for i, v := range gs.Enum {
+			literals[i] = tsLiteral(v)
+		}
+		return strings.Join(literals, " | ")
+	}
+
+	switch gs.Type {
+	case "string":
+		if gs.Format == "binary" {
+			return "Blob"
+		}
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	}
+	return "unknown"
+}
+
+// tsRef is the type of a top level schema, such as a request body, which
+// refers to objects by name as ref does for Go. Names are qualified by
+// namespace, unless it is empty.
+func tsRef(gs *GenSchema, namespace string) string {
+	switch {
+	case gs == nil:
+		return "undefined"
+	case gs.IsDefinedElsewhere || (gs.IsObject && len(gs.Variants) == 0):
+		return tsQualify(gs.ReferenceType, namespace)
+	case gs.IsSlice:
+		return fmt.Sprintf("Array<%s>", tsRef(gs.Items, namespace))
+	}
+	return tsInline(gs, "", namespace)
+}
+
+func tsQualify(name string, namespace string) string {
+	if len(namespace) > 0 {
+		return namespace + "." + name
+	}
+	return name
+}
+
+// tsDeclaration is the right hand side of the type declared in types.ts for
+// a named schema.
+func tsDeclaration(gs *GenSchema) string {
+	if gs.IsSlice {
+		return tsNullable(gs, fmt.Sprintf("Array<%s>", tsRef(gs.Items, "")))
+	}
+	declared := *gs
+	declared.IsDefinedElsewhere = false
+	return tsInline(&declared, "", "")
+}
+
+// tsInline is the type of a schema written out in full, as schema.tmpl does
+// for Go, apart from the items of arrays, which are also written out rather
+// than referred to by a name that isn't declared. indent is that of the line
+// the type starts on.
+func tsInline(gs *GenSchema, indent string, namespace string) string {
+	if gs.IsDefinedElsewhere {
+		return tsQualify(gs.ReferenceType, namespace)
+	}
+
+	var t string
+	switch {
+	case gs.IsSlice:
+		if gs.Items.IsObject && !gs.Items.IsDefinedElsewhere {
+			t = fmt.Sprintf("Array<%s>", tsInline(gs.Items, indent, namespace))
+		} else {
+			t = fmt.Sprintf("Array<%s>", tsRef(gs.Items, namespace))
+		}
+	case gs.IsObject && (len(gs.Properties) > 0 || len(gs.Variants) == 0):
+		t = tsObject(gs, indent, namespace)
+	case gs.IsPrimitive && len(gs.Variants) == 0:
+		t = tsPrimitive(gs)
+	}
+
+	if len(gs.Variants) > 0 {
+		union := tsVariants(gs, indent, namespace)
+		if len(t) > 0 {
+			t = fmt.Sprintf("%s & (%s)", t, union)
+		} else {
+			t = union
+		}
+	}
+	if len(t) == 0 {
+		t = "unknown"
+	}
+	return tsNullable(gs, t)
+}
+
+func tsNullable(gs *GenSchema, t string) string {
+	if gs.Nullable {
+		return t + " | null"
+	}
+	return t
+}
+
+// tsObject is an object literal type with the properties of gs, sorted by
+// name.
+func tsObject(gs *GenSchema, indent string, namespace string) string {
+	if len(gs.Properties) == 0 {
+		return "{ [key: string]: unknown }"
+	}
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, p := range gs.SortedProperties() {
+		inner := indent + "  "
+		b.WriteString(tsDoc(inner, p.Deprecated, p.Description))
+		optional := "?"
+		if p.Required {
+			optional = ""
+		}
+		fmt.Fprintf(&b, "%s%s%s: %s;\n", inner, tsKey(p.ReceiverName), optional, tsInline(p, inner, namespace))
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+// tsVariants is the union of the oneOf or anyOf variants of gs. With a
+// discriminator, each variant is narrowed to the values that select it, so
+// that checking the property tells TypeScript which variant it is.
+func tsVariants(gs *GenSchema, indent string, namespace string) string {
+	var variants []string
+	mapped := map[string]bool{}
+	for _, v := range gs.Variants {
+		t := tsInline(v, indent, namespace)
+		if gs.Discriminator != nil && v.IsDefinedElsewhere {
+			var values []string
+			for _, m := range gs.Discriminator.Mapping {
+				if m.Component == v.ReferenceType {
+					values = append(values, tsString(m.Value))
+				}
+			}
+			if len(values) > 0 {
+				mapped[v.ReferenceType] = true
+				t = fmt.Sprintf("(%s & { %s: %s })", t, tsKey(gs.Discriminator.PropertyName), strings.Join(values, " | "))
+			}
+		}
+		variants = append(variants, t)
+	}
+
+	// components only named by the mapping are variants too
+	if gs.Discriminator != nil {
+		for _, m := range gs.Discriminator.Mapping {
+			if !mapped[m.Component] {
+				mapped[m.Component] = true
+				variants = append(variants, fmt.Sprintf("(%s & { %s: %s })", tsQualify(m.Component, namespace), tsKey(gs.Discriminator.PropertyName), tsString(m.Value)))
+			}
+		}
+	}
+	return strings.Join(variants, " | ")
+}
+
+// tsStatus is the type of a response's status: the code itself, or number
+// for a range or default.
+func tsStatus(status string) string {
+	if isStatusCode(status) {
+		return status
+	}
+	return "number"
+}
+
+// tsResponseBody is the type of a response's body as the client returns it.
+func tsResponseBody(r GenResponse, namespace string) string {
+	switch {
+	case r.IsBinary:
+		return "Blob"
+	case r.IsEventStream:
+		return "ReadableStream<Uint8Array> | null"
+	case r.IsText || r.IsXML:
+		return "string"
+	case len(r.ContentType) == 0:
+		return "undefined"
+	case r.Body == nil:
+		return "unknown"
+	}
+	return tsRef(r.Body, namespace)
+}
+
+// tsResponseKind is how the client reads a response's body.
+func tsResponseKind(r GenResponse) string {
+	switch {
+	case r.IsBinary:
+		return "blob"
+	case r.IsEventStream:
+		return "stream"
+	case r.IsText || r.IsXML:
+		return "text"
+	case len(r.ContentType) == 0:
+		return "none"
+	}
+	return "json"
+}
@@ -0,0 +1,138 @@
+package generator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeScriptTypes(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		// the declaration of the schema, named S, in types.ts
+		expected string
+	}{
+		{
+			name:   "object",
+			schema: `{type: object, required: [name], properties: {name: {type: string, description: a name}, size: {type: integer}, x-tag: {type: string}}}`,
+			expected: `export interface S {
+  /**
+   * a name
+   */
+  name: string;
+  size?: number;
+  "x-tag"?: string;
+}`,
+		},
+		{
+			name:     "enum",
+			schema:   `{type: string, enum: [a, b]}`,
+			expected: `export type S = "a" | "b";`,
+		},
+		{
+			name:     "nullable binary",
+			schema:   `{type: string, format: binary, nullable: true}`,
+			expected: `export type S = Blob | null;`,
+		},
+		{
+			name:     "array of references",
+			schema:   `{type: array, items: {$ref: "#/components/schemas/T"}}`,
+			expected: `export type S = Array<T>;`,
+		},
+		{
+			name:     "free-form object",
+			schema:   `{type: object}`,
+			expected: `export interface S { [key: string]: unknown }`,
+		},
+		{
+			name:   "deprecated property",
+			schema: `{type: object, properties: {old: {type: boolean, deprecated: true}}}`,
+			expected: `export interface S {
+  /**
+   * @deprecated marked as deprecated in the API specification.
+   */
+  old?: boolean;
+}`,
+		},
+		{
+			name:     "discriminated variants",
+			schema:   `{oneOf: [{$ref: "#/components/schemas/T"}, {$ref: "#/components/schemas/U"}], discriminator: {propertyName: kind, mapping: {t: "#/components/schemas/T", u: "#/components/schemas/U"}}}`,
+			expected: `export type S = (T & { kind: "t" }) | (U & { kind: "u" });`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := walk(t, fmt.Sprintf(`
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths: {}
+components:
+	schemas:
+		S: %s
+		T: {type: object, properties: {kind: {type: string}}}
+		U: {type: object, properties: {kind: {type: string}}}
+`, c.schema))
+
+			dir := t.TempDir()
+			GenerateTypeScriptFiles(w, Config{OutputDir: dir})
+			assert.Contains(t, readOutput(t, dir, "types.ts"), c.expected)
+		})
+	}
+}
+
+func TestTypeScriptClient(t *testing.T) {
+	w := walk(t, `
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+	/pets/{id}:
+		get:
+			operationId: getPet
+			security: [{token: []}]
+			parameters:
+				- {name: id, in: path, required: true, schema: {type: string}}
+				- {name: tags, in: query, explode: false, schema: {type: array, items: {type: string}}}
+			responses:
+				"200":
+					description: ok
+					content:
+						application/json: {schema: {$ref: "#/components/schemas/Pet"}}
+				"404": {description: missing}
+				default:
+					description: error
+					content:
+						text/plain: {schema: {type: string}}
+components:
+	securitySchemes:
+		token: {type: http, scheme: bearer}
+	schemas:
+		Pet: {type: object, properties: {name: {type: string}}}
+`)
+
+	dir := t.TempDir()
+	GenerateTypeScriptFiles(w, Config{OutputDir: dir})
+	client := readOutput(t, dir, "client.ts")
+
+	expected := []string{
+		`export interface GetPetParameters {
+  id: string;
+  tags?: Array<string>;
+}`,
+		`export type GetPetResponse =
+  | { status: 200; contentType: "application/json"; body: types.Pet; headers: Headers }
+  | { status: 404; contentType: ""; body: undefined; headers: Headers }
+  | { status: number; contentType: "text/plain"; body: string; headers: Headers };`,
+		`getPet(params: GetPetParameters, init?: RequestInit): Promise<GetPetResponse> {`,
+		`{ name: "tags", key: "tags", in: "query", style: "form", explode: false, allowReserved: false },`,
+		`security: [
+      ["token"],
+    ],`,
+		`{ status: "default", contentType: "text/plain", kind: "text" },`,
+	}
+	for _, e := range expected {
+		assert.Contains(t, client, e)
+	}
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/mllrjb/hackathon-go-openapi-v3/generated/component"
 	"github.com/mllrjb/hackathon-go-openapi-v3/generated/operation"
@@ -10,18 +12,33 @@ import (
 	"github.com/mllrjb/hackathon-go-openapi-v3/generated"
 )
 
+// server implements the operations from examples/demo/requests.yaml; the
+// rest respond with 501 via generated.Unimplemented.
+type server struct {
+	generated.Unimplemented
+}
+
+func (s *server) CreateItems_VndItem(ctx context.Context, params operation.CreateItemsParameters, body component.Item) (operation.Responder, error) {
+	return operation.StatusCodeResponder(204), nil
+}
+
+func (s *server) CreateItems_VndItems(ctx context.Context, params operation.CreateItemsParameters, body []component.Item) (operation.Responder, error) {
+	return operation.JsonResponder(201, "application/vnd.foo.bar+json", "whatever"), nil
+}
+
 func main() {
-	generated.CreateItemsHandler_VndItem = operation.CreateItemsHandler_VndItemFunc(func(params operation.CreateItemsParameters, body component.Item) operation.Responder {
-		return operation.StatusCodeResponder(204)
-	})
-	generated.CreateItemsHandler_VndItems = operation.CreateItemsHandler_VndItemsFunc(func(params operation.CreateItemsParameters, body []component.Item) operation.Responder {
-		return operation.JsonResponder(201, "application/vnd.foo.bar+json", "whatever")
-	})
 	address := "127.0.0.1:9535"
-	server := generated.NewServer("127.0.0.1:9535")
+	srv := generated.NewServer(address,
+		generated.WithHandler(generated.NewRouter(&server{})),
+		generated.WithShutdownTimeout(10*time.Second),
+	)
 
 	fmt.Printf("Listening on %s\n", address)
-	server.ListenAndServe()
+	err := srv.Run(context.Background())
+	if err != nil {
+		fmt.Printf("server stopped: %v\n", err)
+		os.Exit(1)
+	}
 
 	os.Exit(0)
 }
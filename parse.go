@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 
@@ -12,41 +14,292 @@ import (
 	"github.com/googleapis/gnostic/compiler"
 )
 
-// const filepath = "examples/demo/components.yaml"
+// other examples:
+//   -spec examples/demo/components.yaml
+//   -spec examples/CaseAPI/cases.yaml
+//   -spec examples/demo/polymorphism.yaml
+const defaultSpec = "examples/demo/requests.yaml"
 
-// const filepath = "examples/CaseAPI/cases.yaml"
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(validate(os.Args[2:]))
+		case "diff":
+			os.Exit(diff(os.Args[2:]))
+		case "schema":
+			os.Exit(schema(os.Args[2:]))
+		}
+	}
+
+	var filepath, lang string
+	var config generator.Config
+	flag.StringVar(&filepath, "spec", defaultSpec, "OpenAPI document to generate from")
+	flag.StringVar(&config.TemplateDir, "templates", "", "directory of templates overriding the embedded defaults")
+	flag.StringVar(&config.OutputDir, "out", "generated", "output directory")
+	flag.StringVar(&config.PackagePath, "package", "github.com/mllrjb/hackathon-go-openapi-v3/generated", "import path of the output directory")
+	flag.BoolVar(&config.SplitByTag, "split-by-tag", false, "generate one server interface per OpenAPI tag")
+	flag.StringVar(&lang, "lang", "go", "what to generate: go for the server, typescript for types and a client, or proto for a gRPC service")
+	flag.Parse()
 
-// const filepath = "examples/demo/polymorphism.yaml"
+	w, err := load(filepath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
-const filepath = "examples/demo/requests.yaml"
+	switch lang {
+	case "go":
+		generator.GenerateFiles(w, config)
+	case "typescript":
+		generator.GenerateTypeScriptFiles(w, config)
+	case "proto":
+		generator.GenerateProtoFiles(w, config)
+	default:
+		fmt.Printf("unknown language %s\n", lang)
+		os.Exit(2)
+	}
 
-func main() {
+	os.Exit(0)
+}
+
+// read reads the raw spec at filepath. An OpenAPI 3.1 or Swagger 2.0 spec
+// is rewritten as OpenAPI 3.0, with diagnostics for what couldn't be carried
+// over.
+func read(filepath string) (interface{}, []byte, []parser.Diagnostic, error) {
 	bytes, err := compiler.ReadBytesForFile(filepath)
 	if err != nil {
-		fmt.Printf("unable to read bytes from %s %s\n", filepath, err)
-		os.Exit(1)
+		return nil, nil, nil, fmt.Errorf("unable to read bytes from %s %s", filepath, err)
 	}
 	info, err := compiler.ReadInfoFromBytes(filepath, bytes)
 	if err != nil {
-		fmt.Printf("unable to read info from %s %s\n", filepath, err)
-		os.Exit(1)
+		return nil, nil, nil, fmt.Errorf("unable to read info from %s %s", filepath, err)
+	}
+
+	var diags []parser.Diagnostic
+	switch {
+	case parser.IsOpenAPI31(info):
+		info, diags = parser.DowngradeOpenAPI31(info)
+	case parser.IsSwagger2(info):
+		info, diags = parser.ConvertSwagger2(info)
+	}
+	parser.Locate(diags, filepath, bytes)
+	return info, bytes, diags, nil
+}
+
+// load reads, parses and traverses the spec at filepath, printing anything
+// of a 3.1 spec that is ignored.
+func load(filepath string) (parser.Walker, error) {
+	info, _, diags, err := read(filepath)
+	if err != nil {
+		return parser.Walker{}, err
+	}
+	for _, d := range diags {
+		fmt.Fprintln(os.Stderr, d)
 	}
 
 	document, err := openapi_v3.NewDocument(info, compiler.NewContext("$root", nil))
 	if err != nil {
-		fmt.Printf("unable to parse document %s %s\n", filepath, err)
-		os.Exit(1)
+		return parser.Walker{}, fmt.Errorf("unable to parse document %s %s", filepath, err)
 	}
 
 	w := parser.NewWalker(document)
+	w.SetSource(info)
 
 	err = w.Traverse()
 	if err != nil {
-		fmt.Printf("unable to traverse models %s %s\n", filepath, err)
-		os.Exit(1)
+		return parser.Walker{}, fmt.Errorf("unable to traverse models %s %s", filepath, err)
 	}
+	return w, nil
+}
 
-	generator.GenerateFiles(w)
+// validate implements `validate [-json] [-strict] spec.yaml`, returning the
+// exit code: 1 if the spec has errors (or warnings, with -strict), 2 if it
+// can't be read at all.
+func validate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "write the diagnostics as JSON")
+	strict := flags.Bool("strict", false, "fail on warnings as well as errors")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s validate [-json] [-strict] spec.yaml\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	filepath := flags.Arg(0)
 
-	os.Exit(0)
+	info, bytes, diags, err := read(filepath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	w := parser.NewWalker(nil)
+	w.SetSource(info)
+	diags = append(diags, w.Validate()...)
+
+	document, err := openapi_v3.NewDocument(info, compiler.NewContext("$root", nil))
+	if err != nil {
+		diags = append(diags, w.DocumentDiagnostics(err)...)
+	}
+
+	// the generator only gets as far as traversing a spec without errors,
+	// so only then is it worth checking what it makes of it
+	if !hasErrors(diags, false) {
+		w = parser.NewWalker(document)
+		w.SetSource(info)
+		if err := w.Traverse(); err != nil {
+			diags = append(diags, parser.Diagnostic{
+				Severity: parser.SeverityError,
+				Code:     "unsupported",
+				Message:  err.Error(),
+			})
+		}
+	}
+
+	if err := parser.Locate(diags, filepath, bytes); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to locate diagnostics in %s %s\n", filepath, err)
+	}
+
+	errorCount, warningCount := 0, 0
+	for _, d := range diags {
+		if d.Severity == parser.SeverityError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(struct {
+			Errors      int                 `json:"errors"`
+			Warnings    int                 `json:"warnings"`
+			Diagnostics []parser.Diagnostic `json:"diagnostics"`
+		}{errorCount, warningCount, append([]parser.Diagnostic{}, diags...)})
+	} else {
+		for _, d := range diags {
+			fmt.Println(d)
+		}
+		fmt.Printf("%s: %d error(s), %d warning(s)\n", filepath, errorCount, warningCount)
+	}
+
+	if hasErrors(diags, *strict) {
+		return 1
+	}
+	return 0
+}
+
+func hasErrors(diags []parser.Diagnostic, strict bool) bool {
+	for _, d := range diags {
+		if d.Severity == parser.SeverityError || strict {
+			return true
+		}
+	}
+	return false
+}
+
+// diff implements `diff [-json] [-all] old.yaml new.yaml`, returning the exit
+// code: 1 if there are breaking changes, 2 if either spec can't be loaded.
+func diff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "write the changes as JSON")
+	all := flags.Bool("all", false, "report non-breaking changes as well")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s diff [-json] [-all] old.yaml new.yaml\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	prev, err := load(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	next, err := load(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	changes := []parser.Change{}
+	breaking := 0
+	for _, change := range parser.Diff(&prev, &next) {
+		if change.Breaking {
+			breaking++
+		}
+		if change.Breaking || *all {
+			changes = append(changes, change)
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(struct {
+			Breaking int             `json:"breaking"`
+			Changes  []parser.Change `json:"changes"`
+		}{breaking, changes})
+	} else {
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+		fmt.Printf("%d breaking change(s)\n", breaking)
+	}
+
+	if breaking > 0 {
+		return 1
+	}
+	return 0
+}
+
+// schema implements `schema [-out dir] [-bundle file] [-for direction]
+// spec.yaml`, returning the exit code: 1 if the schemas can't be written, 2
+// if the spec can't be loaded.
+func schema(args []string) int {
+	flags := flag.NewFlagSet("schema", flag.ExitOnError)
+	outputDir := flags.String("out", "schemas", "directory to write a JSON Schema per component to")
+	bundle := flags.String("bundle", "", "write a single JSON Schema with the components in $defs to this file instead")
+	direction := flags.String("for", "", "request or response, to leave out readOnly or writeOnly properties")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s schema [-out dir] [-bundle file] [-for request|response] spec.yaml\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 || (*direction != "" && *direction != "request" && *direction != "response") {
+		flags.Usage()
+		return 2
+	}
+
+	w, err := load(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var diags []parser.Diagnostic
+	if len(*bundle) > 0 {
+		diags, err = generator.GenerateJSONSchemaBundle(w, *bundle, *direction)
+	} else {
+		diags, err = generator.GenerateJSONSchemas(w, *outputDir, *direction)
+	}
+	if bytes, err := compiler.ReadBytesForFile(flags.Arg(0)); err == nil {
+		parser.Locate(diags, flags.Arg(0), bytes)
+	}
+	for _, d := range diags {
+		fmt.Fprintln(os.Stderr, d)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
 }
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Info is the info object of the document.
+type Info struct {
+	Title       string
+	Version     string
+	Description string
+}
+
+// GetInfo returns the document's title, version and description.
+func (o *Walker) GetInfo() Info {
+	if o.document == nil || o.document.Info == nil {
+		return Info{}
+	}
+	return Info{
+		Title:       o.document.Info.Title,
+		Version:     o.document.Info.Version,
+		Description: o.document.Info.Description,
+	}
+}
+
+// Bundle returns the raw document (see SetSource) with every local $ref
+// replaced by a copy of the value it points to, so that the result can be
+// read without following references. A $ref back into a value that is
+// already being expanded is left as it is, since a recursive schema can't be
+// inlined; components are kept so that those references still resolve.
+func (o *Walker) Bundle() yaml.MapSlice {
+	bundled, _ := o.inlineRefs(o.source, nil).(yaml.MapSlice)
+	return bundled
+}
+
+// inlineRefs copies value with its local references inlined. expanding holds
+// the references currently being inlined, outermost first.
+func (o *Walker) inlineRefs(value interface{}, expanding []string) interface{} {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		if ref, ok := localRef(v); ok {
+			for _, r := range expanding {
+				if r == ref {
+					return v
+				}
+			}
+			target := o.sourceValue(refKeys(ref)...)
+			if target == nil {
+				return v
+			}
+			return o.inlineRefs(target, append(expanding, ref))
+		}
+
+		bundled := make(yaml.MapSlice, len(v))
+		for i, item := range v {
+			bundled[i] = yaml.MapItem{Key: item.Key, Value: o.inlineRefs(item.Value, expanding)}
+		}
+		return bundled
+	case []interface{}:
+		bundled := make([]interface{}, len(v))
+		for i, item := range v {
+			bundled[i] = o.inlineRefs(item, expanding)
+		}
+		return bundled
+	}
+	return value
+}
+
+// localRef returns the target of m if it is a reference object pointing
+// within the document.
+func localRef(m yaml.MapSlice) (string, bool) {
+	for _, item := range m {
+		if key, _ := item.Key.(string); key == "$ref" {
+			ref, _ := item.Value.(string)
+			return ref, strings.HasPrefix(ref, "#/")
+		}
+	}
+	return "", false
+}
+
+// refKeys splits a local reference into the map keys of its JSON pointer.
+func refKeys(ref string) []string {
+	keys := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+	for i, key := range keys {
+		keys[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+	}
+	return keys
+}
@@ -0,0 +1,587 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Change is a difference between two versions of a spec that affects its
+// clients.
+type Change struct {
+	// Breaking changes may stop an existing client from working.
+	Breaking bool `json:"breaking"`
+	// Code identifies the kind of change, e.g. request-property-required.
+	Code string `json:"code"`
+	// Operation is the changed operation, e.g. "GET /pets/{id}", or empty
+	// for a change to a component schema, which is reported once however
+	// many operations use it.
+	Operation string `json:"operation,omitempty"`
+	// Location is the changed part of the operation or component, e.g.
+	// "response 200 application/json /name".
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (c Change) String() string {
+	kind := "non-breaking"
+	if c.Breaking {
+		kind = "breaking"
+	}
+
+	var where []string
+	if len(c.Operation) > 0 {
+		where = append(where, c.Operation)
+	}
+	if len(c.Location) > 0 {
+		where = append(where, c.Location)
+	}
+	if len(where) == 0 {
+		return fmt.Sprintf("%s: %s [%s]", kind, c.Message, c.Code)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", kind, strings.Join(where, " "), c.Message, c.Code)
+}
+
+// Diff compares the operations of two traversed walkers, and the schemas
+// they use, returning what changed from old to new. Whether a schema change
+// is breaking depends on which way the data goes: a new required property
+// breaks clients sending requests, while a removed property breaks clients
+// reading responses.
+func Diff(prev *Walker, next *Walker) []Change {
+	d := &differ{visited: map[visit]bool{}}
+
+	oldOps := operationsByRoute(prev.GetOperations())
+	newOps := operationsByRoute(next.GetOperations())
+
+	routes := []string{}
+	for route := range oldOps {
+		routes = append(routes, route)
+	}
+	for route := range newOps {
+		if _, ok := oldOps[route]; !ok {
+			routes = append(routes, route)
+		}
+	}
+	sort.Strings(routes)
+
+	for _, route := range routes {
+		oldOp, newOp := oldOps[route], newOps[route]
+		switch {
+		case newOp == nil:
+			d.report(true, "operation-removed", operationName(oldOp), "", "the operation was removed")
+		case oldOp == nil:
+			d.report(false, "operation-added", operationName(newOp), "", "the operation was added")
+		default:
+			d.operation(oldOp, newOp)
+		}
+	}
+
+	return d.changes
+}
+
+type differ struct {
+	changes []Change
+	// visited holds the pairs of schemas already compared, so that
+	// components are only reported once and recursive schemas end.
+	visited map[visit]bool
+}
+
+type visit struct {
+	prev, next SchemaModel
+	request    bool
+}
+
+func (d *differ) report(breaking bool, code string, operation string, location string, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{
+		Breaking:  breaking,
+		Code:      code,
+		Operation: operation,
+		Location:  location,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+var pathParameter = regexp.MustCompile(`\{[^}]*\}`)
+
+// operationsByRoute keys operations by method and path, ignoring the names
+// of path parameters, since renaming one doesn't change the route.
+func operationsByRoute(operations []*Operation) map[string]*Operation {
+	routes := map[string]*Operation{}
+	for _, op := range operations {
+		routes[pathParameter.ReplaceAllString(op.Path, "{}")+" "+op.Method] = op
+	}
+	return routes
+}
+
+func operationName(op *Operation) string {
+	return op.Method + " " + op.Path
+}
+
+func (d *differ) operation(prev *Operation, next *Operation) {
+	name := operationName(next)
+
+	if prev.OperationID != next.OperationID {
+		d.report(false, "operation-id-changed", name, "", "operationId changed from %s to %s, renaming the generated method", prev.OperationID, next.OperationID)
+	}
+	if !prev.Deprecated && next.Deprecated {
+		d.report(false, "operation-deprecated", name, "", "the operation was deprecated")
+	}
+	d.security(name, prev.Security, next.Security)
+	d.parameters(name, prev, next)
+	d.requests(name, prev.Requests, next.Requests)
+	d.responses(name, prev.Responses, next.Responses)
+}
+
+func (d *differ) security(operation string, prev []SecurityRequirement, next []SecurityRequirement) {
+	describe := func(requirement SecurityRequirement) string {
+		var schemes []string
+		for scheme, scopes := range requirement {
+			sort.Strings(scopes)
+			schemes = append(schemes, scheme+"("+strings.Join(scopes, ",")+")")
+		}
+		sort.Strings(schemes)
+		return strings.Join(schemes, " and ")
+	}
+
+	oldAlternatives := map[string]bool{}
+	for _, requirement := range prev {
+		oldAlternatives[describe(requirement)] = true
+	}
+	newAlternatives := map[string]bool{}
+	for _, requirement := range next {
+		newAlternatives[describe(requirement)] = true
+	}
+
+	switch {
+	case len(prev) == 0 && len(next) > 0:
+		d.report(true, "security-added", operation, "", "the operation now requires authentication")
+	case len(prev) > 0 && len(next) == 0:
+		d.report(false, "security-removed", operation, "", "the operation no longer requires authentication")
+	default:
+		// a client satisfying an old alternative still works if that
+		// alternative is still accepted
+		for alternative := range oldAlternatives {
+			if !newAlternatives[alternative] {
+				d.report(true, "security-changed", operation, "", "the security requirement %s is no longer accepted", alternative)
+			}
+		}
+		for alternative := range newAlternatives {
+			if !oldAlternatives[alternative] {
+				d.report(false, "security-changed", operation, "", "the security requirement %s is now accepted", alternative)
+			}
+		}
+	}
+}
+
+func (d *differ) parameters(operation string, oldOp *Operation, newOp *Operation) {
+	key := func(p Parameter) string {
+		return p.In + " " + p.Name
+	}
+
+	// path parameters are matched by position, since they may be renamed
+	pathIndex := func(op *Operation, name string) int {
+		for i, match := range pathParameter.FindAllString(op.Path, -1) {
+			if match == "{"+name+"}" {
+				return i
+			}
+		}
+		return -1
+	}
+
+	prev := map[string]Parameter{}
+	for _, p := range oldOp.Parameters {
+		if p.In == "path" {
+			prev[fmt.Sprintf("path %d", pathIndex(oldOp, p.Name))] = p
+		} else {
+			prev[key(p)] = p
+		}
+	}
+
+	for _, p := range newOp.Parameters {
+		k := key(p)
+		if p.In == "path" {
+			k = fmt.Sprintf("path %d", pathIndex(newOp, p.Name))
+		}
+		location := fmt.Sprintf("%s parameter %s", p.In, p.Name)
+
+		previous, ok := prev[k]
+		delete(prev, k)
+		if !ok {
+			if p.Required {
+				d.report(true, "required-parameter-added", operation, location, "a required parameter was added")
+			} else {
+				d.report(false, "parameter-added", operation, location, "an optional parameter was added")
+			}
+			continue
+		}
+
+		if !previous.Required && p.Required {
+			d.report(true, "parameter-required", operation, location, "the parameter is now required")
+		}
+		if previous.Required && !p.Required {
+			d.report(false, "parameter-optional", operation, location, "the parameter is now optional")
+		}
+		if previous.Style != p.Style || previous.Explode != p.Explode {
+			d.report(true, "parameter-style-changed", operation, location, "the parameter is now serialized with style %s, explode %t", p.Style, p.Explode)
+		}
+		d.schema(operation, location, previous.Schema, p.Schema, true)
+	}
+
+	removed := []string{}
+	for _, p := range prev {
+		removed = append(removed, fmt.Sprintf("%s parameter %s", p.In, p.Name))
+	}
+	sort.Strings(removed)
+	for _, location := range removed {
+		d.report(true, "parameter-removed", operation, location, "the parameter was removed")
+	}
+}
+
+func (d *differ) requests(operation string, prev []Request, next []Request) {
+	oldBodies := map[string]Request{}
+	for _, r := range prev {
+		oldBodies[r.Accept] = r
+	}
+
+	for _, r := range next {
+		location := "request " + r.Accept
+		previous, ok := oldBodies[r.Accept]
+		delete(oldBodies, r.Accept)
+		if !ok {
+			if len(prev) == 0 {
+				d.report(true, "request-body-added", operation, location, "a request body is now expected")
+			} else {
+				d.report(false, "request-media-type-added", operation, location, "the media type is now accepted")
+			}
+			continue
+		}
+		d.schema(operation, location, previous.Body, r.Body, true)
+	}
+
+	removed := []string{}
+	for mediaType := range oldBodies {
+		removed = append(removed, mediaType)
+	}
+	sort.Strings(removed)
+	for _, mediaType := range removed {
+		d.report(true, "request-media-type-removed", operation, "request "+mediaType, "the media type is no longer accepted")
+	}
+}
+
+func (d *differ) responses(operation string, prev []Response, next []Response) {
+	type key struct{ status, contentType string }
+	oldStatuses := map[string]bool{}
+	oldResponses := map[key]Response{}
+	for _, r := range prev {
+		oldStatuses[r.StatusCode] = true
+		oldResponses[key{r.StatusCode, r.ContentType}] = r
+	}
+	newStatuses := map[string]bool{}
+	for _, r := range next {
+		newStatuses[r.StatusCode] = true
+	}
+
+	for _, r := range next {
+		location := strings.TrimSpace("response " + r.StatusCode + " " + r.ContentType)
+		previous, ok := oldResponses[key{r.StatusCode, r.ContentType}]
+		delete(oldResponses, key{r.StatusCode, r.ContentType})
+		if !ok {
+			if oldStatuses[r.StatusCode] {
+				d.report(false, "response-media-type-added", operation, location, "the media type may now be returned")
+			} else {
+				d.report(false, "response-added", operation, location, "the response was added")
+			}
+			continue
+		}
+
+		d.headers(operation, location, previous.Headers, r.Headers)
+		d.schema(operation, location, previous.Body, r.Body, false)
+	}
+
+	removed := []Response{}
+	for _, r := range oldResponses {
+		removed = append(removed, r)
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		if removed[i].StatusCode != removed[j].StatusCode {
+			return removed[i].StatusCode < removed[j].StatusCode
+		}
+		return removed[i].ContentType < removed[j].ContentType
+	})
+	for _, r := range removed {
+		location := strings.TrimSpace("response " + r.StatusCode + " " + r.ContentType)
+		if newStatuses[r.StatusCode] {
+			d.report(true, "response-media-type-removed", operation, location, "the media type is no longer returned")
+		} else if strings.HasPrefix(r.StatusCode, "2") {
+			// clients handling errors cope with a missing error response,
+			// but not with a missing success
+			d.report(true, "response-removed", operation, location, "the response was removed")
+		} else {
+			d.report(false, "response-removed", operation, location, "the response was removed")
+		}
+	}
+}
+
+func (d *differ) headers(operation string, location string, prev []Header, next []Header) {
+	oldHeaders := map[string]Header{}
+	for _, h := range prev {
+		oldHeaders[strings.ToLower(h.Name)] = h
+	}
+
+	for _, h := range next {
+		headerLocation := location + " header " + h.Name
+		previous, ok := oldHeaders[strings.ToLower(h.Name)]
+		delete(oldHeaders, strings.ToLower(h.Name))
+		if !ok {
+			d.report(false, "response-header-added", operation, headerLocation, "the header was added")
+			continue
+		}
+		if previous.Required && !h.Required {
+			d.report(true, "response-header-optional", operation, headerLocation, "the header is no longer always returned")
+		}
+		d.schema(operation, headerLocation, previous.Schema, h.Schema, false)
+	}
+
+	removed := []string{}
+	for _, h := range oldHeaders {
+		removed = append(removed, h.Name)
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		d.report(oldHeaders[strings.ToLower(name)].Required, "response-header-removed", operation, location+" header "+name, "the header was removed")
+	}
+}
+
+// common returns the fields shared by every kind of schema model.
+func common(model SchemaModel) *CommonSchemaModel {
+	switch m := model.(type) {
+	case *StructSchemaModel:
+		return &m.CommonSchemaModel
+	case *ArraySchemaModel:
+		return &m.CommonSchemaModel
+	case *PrimitiveSchemaModel:
+		return &m.CommonSchemaModel
+	}
+	return &CommonSchemaModel{}
+}
+
+// schema compares two versions of a schema. When request is set, the data
+// is sent by clients, so tightening the schema breaks them; otherwise it is
+// received by clients, so loosening it does.
+func (d *differ) schema(operation string, location string, prev SchemaModel, next SchemaModel, request bool) {
+	direction := "responses"
+	if request {
+		direction = "requests"
+	}
+
+	switch {
+	case prev == nil && next == nil:
+		return
+	case prev == nil:
+		d.report(!request, "schema-added", operation, location, "a schema was added where any value was allowed")
+		return
+	case next == nil:
+		d.report(request, "schema-removed", operation, location, "the schema was removed, allowing any value")
+		return
+	}
+
+	if d.visited[visit{prev, next, request}] {
+		return
+	}
+	d.visited[visit{prev, next, request}] = true
+
+	// changes within a component are reported against the component
+	if prev.IsComponent() && next.IsComponent() && prev.GetComponentName() == next.GetComponentName() {
+		operation = ""
+		location = fmt.Sprintf("%s (in %s)", next.GetComponentName(), direction)
+	}
+
+	if prev.GetType() != next.GetType() {
+		d.report(true, "type-changed", operation, location, "the type changed from %s to %s", typeName(prev), typeName(next))
+		return
+	}
+
+	oldCommon, newCommon := common(prev), common(next)
+	if oldCommon.Nullable != newCommon.Nullable {
+		// a loosened schema breaks clients reading it
+		loosened := newCommon.Nullable
+		if loosened {
+			d.report(!request, "nullable-changed", operation, location, "null is now allowed")
+		} else {
+			d.report(request, "nullable-changed", operation, location, "null is no longer allowed")
+		}
+	}
+	d.enum(operation, location, oldCommon.Enum, newCommon.Enum, request)
+
+	switch o := prev.(type) {
+	case *PrimitiveSchemaModel:
+		n := next.(*PrimitiveSchemaModel)
+		if o.Format != n.Format {
+			d.report(true, "format-changed", operation, location, "the format changed from %q to %q", o.Format, n.Format)
+		}
+		d.bound(operation, location, "minLength", o.MinLength, n.MinLength, true, request)
+		d.bound(operation, location, "maxLength", o.MaxLength, n.MaxLength, false, request)
+	case *ArraySchemaModel:
+		n := next.(*ArraySchemaModel)
+		d.bound(operation, location, "minItems", o.MinItems, n.MinItems, true, request)
+		d.bound(operation, location, "maxItems", o.MaxItems, n.MaxItems, false, request)
+		d.schema(operation, location+" /items", o.Items, n.Items, request)
+	case *StructSchemaModel:
+		n := next.(*StructSchemaModel)
+		d.properties(operation, location, o, n, request)
+	}
+
+	d.variants(operation, location, prev.GetDiscriminator(), next.GetDiscriminator(), request)
+}
+
+func typeName(model SchemaModel) string {
+	if len(model.GetType()) == 0 {
+		return "any"
+	}
+	return model.GetType()
+}
+
+func (d *differ) enum(operation string, location string, prev []interface{}, next []interface{}, request bool) {
+	values := func(enum []interface{}) map[string]bool {
+		set := map[string]bool{}
+		for _, v := range enum {
+			set[fmt.Sprint(v)] = true
+		}
+		return set
+	}
+	oldValues, newValues := values(prev), values(next)
+
+	var removed, added []string
+	for v := range oldValues {
+		if !newValues[v] && len(next) > 0 {
+			removed = append(removed, v)
+		}
+	}
+	for v := range newValues {
+		if !oldValues[v] || len(prev) == 0 {
+			added = append(added, v)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	if len(prev) == 0 && len(next) > 0 {
+		d.report(request, "enum-added", operation, location, "the values are now restricted to %s", strings.Join(added, ", "))
+		return
+	}
+	if len(prev) > 0 && len(next) == 0 {
+		d.report(!request, "enum-removed", operation, location, "the values are no longer restricted")
+		return
+	}
+	if len(removed) > 0 {
+		d.report(request, "enum-narrowed", operation, location, "the values %s were removed", strings.Join(removed, ", "))
+	}
+	if len(added) > 0 {
+		// clients may not handle values they don't know about
+		d.report(!request, "enum-widened", operation, location, "the values %s were added", strings.Join(added, ", "))
+	}
+}
+
+// bound compares a minimum (or, unless isMin, a maximum) where zero means
+// there is none.
+func (d *differ) bound(operation string, location string, name string, prev int64, next int64, isMin bool, request bool) {
+	if prev == next {
+		return
+	}
+
+	tightened := next > prev
+	if !isMin {
+		tightened = prev == 0 || (next != 0 && next < prev)
+	}
+	if tightened {
+		d.report(request, "constraint-tightened", operation, location, "%s changed from %d to %d", name, prev, next)
+	} else {
+		d.report(!request, "constraint-loosened", operation, location, "%s changed from %d to %d", name, prev, next)
+	}
+}
+
+func (d *differ) properties(operation string, location string, prev *StructSchemaModel, next *StructSchemaModel, request bool) {
+	names := []string{}
+	for name := range prev.Properties {
+		names = append(names, name)
+	}
+	for name := range next.Properties {
+		if _, ok := prev.Properties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propertyLocation := location + " /" + name
+		oldProperty, inOld := prev.Properties[name]
+		newProperty, inNew := next.Properties[name]
+		wasRequired, isRequired := contains(prev.Required, name), contains(next.Required, name)
+
+		switch {
+		case !inNew:
+			// clients may still send it, but can't rely on receiving it
+			d.report(!request, "property-removed", operation, propertyLocation, "the property was removed")
+		case !inOld && isRequired && request:
+			d.report(true, "required-property-added", operation, propertyLocation, "a required property was added")
+		case !inOld:
+			d.report(false, "property-added", operation, propertyLocation, "the property was added")
+		default:
+			if !wasRequired && isRequired {
+				d.report(request, "property-required", operation, propertyLocation, "the property is now required")
+			}
+			if wasRequired && !isRequired {
+				d.report(!request, "property-optional", operation, propertyLocation, "the property is now optional")
+			}
+			d.schema(operation, propertyLocation, oldProperty, newProperty, request)
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// variants compares the component alternatives of a oneOf or anyOf. Inline
+// alternatives have no name to match them by, so they aren't compared.
+func (d *differ) variants(operation string, location string, prev *DiscriminatedSchemaModel, next *DiscriminatedSchemaModel, request bool) {
+	names := func(m *DiscriminatedSchemaModel) map[string]bool {
+		set := map[string]bool{}
+		if m != nil {
+			for _, variant := range m.DiscriminatorSchemas {
+				if variant.IsComponent() {
+					set[variant.GetComponentName()] = true
+				}
+			}
+		}
+		return set
+	}
+	oldNames, newNames := names(prev), names(next)
+
+	var removed, added []string
+	for name := range oldNames {
+		if !newNames[name] {
+			removed = append(removed, name)
+		}
+	}
+	for name := range newNames {
+		if !oldNames[name] {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	for _, name := range removed {
+		d.report(request, "variant-removed", operation, location, "the alternative %s was removed", name)
+	}
+	for _, name := range added {
+		d.report(!request, "variant-added", operation, location, "the alternative %s was added", name)
+	}
+}
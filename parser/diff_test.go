@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	openapi_v3 "github.com/googleapis/gnostic/OpenAPIv3"
+	"github.com/googleapis/gnostic/compiler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// walk parses and traverses an inline spec, as load in parse.go does.
+func walk(t *testing.T, spec string) *Walker {
+	info, _ := readSpec(t, spec)
+	document, err := openapi_v3.NewDocument(info, compiler.NewContext("$root", nil))
+	require.NoError(t, err)
+
+	w := NewWalker(document)
+	w.SetSource(info)
+	require.NoError(t, w.Traverse())
+	return &w
+}
+
+// itemSpec is a spec with a single operation, made of the given extra
+// parameters and request and response schemas.
+func itemSpec(parameters string, request string, response string) string {
+	return fmt.Sprintf(`
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+	/items/{id}:
+		put:
+			operationId: putItem
+			parameters: [{name: id, in: path, required: true, schema: {type: string}}%s]
+			requestBody: {content: {application/json: {schema: %s}}}
+			responses: {"200": {description: ok, content: {application/json: {schema: %s}}}}
+`, parameters, request, response)
+}
+
+const (
+	itemRequest  = `{type: object, properties: {name: {type: string}, kind: {type: string, enum: [a, b]}}}`
+	itemResponse = `{type: object, properties: {id: {type: string}, kind: {type: string, enum: [a, b]}}}`
+)
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		name string
+		next string
+		// breaking or non-breaking, code and location of each change
+		changes []string
+	}{
+		{
+			name:    "unchanged",
+			next:    itemSpec("", itemRequest, itemResponse),
+			changes: []string{},
+		},
+		{
+			name: "parameters",
+			next: itemSpec(", {name: q, in: query, schema: {type: string}}, {name: X-Tenant, in: header, required: true, schema: {type: string}}", itemRequest, itemResponse),
+			changes: []string{
+				"non-breaking parameter-added query parameter q",
+				"breaking required-parameter-added header parameter X-Tenant",
+			},
+		},
+		{
+			name: "request properties",
+			next: itemSpec("", `{type: object, required: [name, size], properties: {name: {type: string}, size: {type: integer}, kind: {type: string, enum: [a, b, c]}}}`, itemResponse),
+			changes: []string{
+				"non-breaking enum-widened request application/json /kind",
+				"breaking property-required request application/json /name",
+				"breaking required-property-added request application/json /size",
+			},
+		},
+		{
+			name: "response properties",
+			next: itemSpec("", itemRequest, `{type: object, required: [id], properties: {id: {type: string}, kind: {type: string, enum: [a, b, c]}, size: {type: integer}}}`),
+			changes: []string{
+				"non-breaking property-required response 200 application/json /id",
+				"breaking enum-widened response 200 application/json /kind",
+				"non-breaking property-added response 200 application/json /size",
+			},
+		},
+		{
+			name: "removed properties",
+			next: itemSpec("", `{type: object, properties: {name: {type: string}}}`, `{type: object, properties: {id: {type: string}}}`),
+			changes: []string{
+				"non-breaking property-removed request application/json /kind",
+				"breaking property-removed response 200 application/json /kind",
+			},
+		},
+		{
+			name: "type",
+			next: itemSpec("", `{type: object, properties: {name: {type: integer}, kind: {type: string, enum: [a, b]}}}`, itemResponse),
+			changes: []string{
+				"breaking type-changed request application/json /name",
+			},
+		},
+	}
+
+	prev := walk(t, itemSpec("", itemRequest, itemResponse))
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := []string{}
+			for _, change := range Diff(prev, walk(t, c.next)) {
+				kind := "non-breaking"
+				if change.Breaking {
+					kind = "breaking"
+				}
+				assert.Equal(t, "PUT /items/{id}", change.Operation)
+				actual = append(actual, fmt.Sprintf("%s %s %s", kind, change.Code, change.Location))
+			}
+			assert.Equal(t, c.changes, actual)
+		})
+	}
+}
+
+func TestDiffOperations(t *testing.T) {
+	prev := walk(t, `
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+	/items:
+		get: {operationId: listItems, responses: {"200": {description: ok}}}
+	/items/{id}:
+		delete: {operationId: deleteItem, parameters: [{name: id, in: path, required: true, schema: {type: string}}], responses: {"204": {description: ok}}}
+`)
+	next := walk(t, `
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+	/items:
+		get: {operationId: getItems, responses: {"200": {description: ok}}}
+		post: {operationId: createItem, responses: {"201": {description: ok}}}
+	/items/{itemId}:
+		delete: {operationId: deleteItem, security: [{token: []}], parameters: [{name: itemId, in: path, required: true, schema: {type: string}}], responses: {"204": {description: ok}}}
+components:
+	securitySchemes:
+		token: {type: http, scheme: bearer}
+`)
+
+	assert.Equal(t, []Change{
+		{Breaking: false, Code: "operation-id-changed", Operation: "GET /items", Message: "operationId changed from listItems to getItems, renaming the generated method"},
+		{Breaking: false, Code: "operation-added", Operation: "POST /items", Message: "the operation was added"},
+		{Breaking: true, Code: "security-added", Operation: "DELETE /items/{itemId}", Message: "the operation now requires authentication"},
+	}, Diff(prev, next))
+}
+
+// TestDiffExitCode runs the diff command, which fails only for breaking
+// changes.
+func TestDiffExitCode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the command")
+	}
+
+	dir := t.TempDir()
+	command := filepath.Join(dir, "parse")
+	build := exec.Command("go", "build", "-o", command, "../parse.go")
+	output, err := build.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	write := func(name string, spec string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(strings.ReplaceAll(spec, "\t", "  ")), 0644))
+		return path
+	}
+	prev := write("prev.yaml", itemSpec("", itemRequest, itemResponse))
+
+	cases := []struct {
+		name string
+		next string
+		code int
+	}{
+		{"unchanged", itemSpec("", itemRequest, itemResponse), 0},
+		{"non-breaking", itemSpec(", {name: q, in: query, schema: {type: string}}", itemRequest, itemResponse), 0},
+		{"breaking", itemSpec("", itemRequest, `{type: object, properties: {id: {type: string}}}`), 1},
+		{"unreadable", "openapi: [", 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			next := write(c.name+".yaml", c.next)
+			output, err := exec.Command(command, "diff", prev, next).CombinedOutput()
+			code := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				code = exitErr.ExitCode()
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, c.code, code, string(output))
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/googleapis/gnostic/compiler"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Component schemas are exported as JSON Schema 2020-12 for tools that
+// don't read OpenAPI. This is the reverse of DowngradeOpenAPI31: nullable
+// becomes a "null" type, the boolean exclusiveMinimum and exclusiveMaximum
+// become numbers, and a discriminator becomes if/then subschemas that pick
+// the variant by the value of its property.
+
+// JSONSchemaDialect is the $schema of exported documents.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchemas returns a JSON Schema document for each component schema of
+// GetModels, keyed by component name. A $ref to another component points at
+// its document, named after it with a .json extension, so the documents are
+// meant to be written side by side.
+//
+// direction is "request", "response" or empty. readOnly properties are left
+// out of request schemas and writeOnly ones out of response schemas; when
+// the direction is empty, both are kept but aren't required, since OpenAPI
+// only requires them in one direction.
+func (o *Walker) JSONSchemas(direction string) (yaml.MapSlice, []Diagnostic) {
+	e := &exporter{walker: o, direction: direction}
+	documents := yaml.MapSlice{}
+	for _, name := range e.components() {
+		e.current = name
+		pointer := jsonPointer("components", "schemas", name)
+		schema := e.schema(o.sourceValue("components", "schemas", name), pointer)
+		documents = append(documents, yaml.MapItem{Key: name, Value: withDialect(schema)})
+	}
+	return documents, e.diags
+}
+
+// JSONSchemaBundle returns a single JSON Schema document with every
+// component schema of GetModels in $defs, and references between them
+// pointing there. direction is as for JSONSchemas.
+func (o *Walker) JSONSchemaBundle(direction string) (yaml.MapSlice, []Diagnostic) {
+	e := &exporter{walker: o, direction: direction, bundle: true}
+	defs := yaml.MapSlice{}
+	for _, name := range e.components() {
+		pointer := jsonPointer("components", "schemas", name)
+		defs = append(defs, yaml.MapItem{Key: name, Value: e.schema(o.sourceValue("components", "schemas", name), pointer)})
+	}
+
+	bundle := yaml.MapSlice{{Key: "$schema", Value: JSONSchemaDialect}}
+	if title := o.GetInfo().Title; len(title) > 0 {
+		bundle = append(bundle, yaml.MapItem{Key: "title", Value: title})
+	}
+	bundle = append(bundle, yaml.MapItem{Key: "$defs", Value: defs})
+	return bundle, e.diags
+}
+
+func withDialect(schema interface{}) yaml.MapSlice {
+	return append(yaml.MapSlice{{Key: "$schema", Value: JSONSchemaDialect}}, mapOf(schema)...)
+}
+
+type exporter struct {
+	walker    *Walker
+	direction string
+	// bundle is set when references point into $defs, rather than at the
+	// document of each component.
+	bundle bool
+	// current is the component whose document is being written.
+	current string
+	diags   []Diagnostic
+}
+
+func (e *exporter) warnf(pointer string, format string, args ...interface{}) {
+	e.diags = append(e.diags, Diagnostic{Severity: SeverityWarning, Code: "unsupported-keyword", Message: fmt.Sprintf(format, args...), Pointer: pointer})
+}
+
+// components returns the names of the component schemas the walker
+// modeled, in document order.
+func (e *exporter) components() []string {
+	var names []string
+	for _, name := range mapKeys(e.walker.sourceValue("components", "schemas")) {
+		if m := e.walker.FindModel(name); m != nil && m.IsComponent() {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// schema rewrites an OpenAPI 3.0 schema as a JSON Schema 2020-12 one.
+func (e *exporter) schema(value interface{}, pointer string) interface{} {
+	m, ok := compiler.UnpackMap(value)
+	if !ok {
+		return value
+	}
+	if ref, ok := compiler.MapValueForKey(m, "$ref").(string); ok {
+		// OpenAPI 3.0 ignores anything alongside a reference
+		return yaml.MapSlice{{Key: "$ref", Value: e.ref(ref, pointer+"/$ref")}}
+	}
+
+	result := yaml.MapSlice{}
+	set := func(key string, value interface{}) {
+		setKey(&result, key, value)
+	}
+
+	nullable, _ := compiler.MapValueForKey(m, "nullable").(bool)
+	for _, item := range m {
+		key := fmt.Sprint(item.Key)
+		child := pointer + jsonPointer(key)
+		switch key {
+		case "nullable", "discriminator":
+			// below, once the rest of the schema is known
+		case "type":
+			if nullable {
+				set(key, []interface{}{item.Value, "null"})
+			} else {
+				set(key, item.Value)
+			}
+		case "enum":
+			values, _ := item.Value.([]interface{})
+			if nullable && !containsNil(values) {
+				values = append(append([]interface{}{}, values...), nil)
+			}
+			set(key, values)
+		case "example":
+			set("examples", []interface{}{item.Value})
+		case "exclusiveMinimum", "exclusiveMaximum":
+			bound := "minimum"
+			if key == "exclusiveMaximum" {
+				bound = "maximum"
+			}
+			if exclusive, _ := item.Value.(bool); exclusive {
+				if limit := compiler.MapValueForKey(m, bound); limit != nil {
+					set(key, limit)
+				}
+			}
+		case "minimum", "maximum":
+			exclusiveKey := "exclusiveMinimum"
+			if key == "maximum" {
+				exclusiveKey = "exclusiveMaximum"
+			}
+			if exclusive, _ := compiler.MapValueForKey(m, exclusiveKey).(bool); !exclusive {
+				set(key, item.Value)
+			}
+		case "format":
+			switch item.Value {
+			case "byte":
+				set("contentEncoding", "base64")
+			case "binary":
+				set("contentMediaType", "application/octet-stream")
+			default:
+				set(key, item.Value)
+			}
+		case "xml", "externalDocs":
+			// only meaningful to OpenAPI
+		case "properties":
+			properties := yaml.MapSlice{}
+			for _, p := range mapOf(item.Value) {
+				if e.omitted(p.Value) {
+					continue
+				}
+				properties = append(properties, yaml.MapItem{Key: p.Key, Value: e.schema(p.Value, child+jsonPointer(fmt.Sprint(p.Key)))})
+			}
+			set(key, properties)
+		case "required":
+			names, _ := item.Value.([]interface{})
+			required := []interface{}{}
+			for _, name := range names {
+				property := compiler.MapValueForKey(mapOf(compiler.MapValueForKey(m, "properties")), fmt.Sprint(name))
+				if !e.omitted(property) && (len(e.direction) > 0 || !e.oneWay(property)) {
+					required = append(required, name)
+				}
+			}
+			if len(required) > 0 {
+				set(key, required)
+			}
+		case "items", "not":
+			set(key, e.schema(item.Value, child))
+		case "additionalProperties":
+			if _, isBool := item.Value.(bool); isBool {
+				set(key, item.Value)
+			} else {
+				set(key, e.schema(item.Value, child))
+			}
+		case "allOf", "anyOf", "oneOf":
+			subschemas, _ := item.Value.([]interface{})
+			converted := make([]interface{}, len(subschemas))
+			for i, s := range subschemas {
+				converted[i] = e.schema(s, fmt.Sprintf("%s/%d", child, i))
+			}
+			set(key, converted)
+		default:
+			set(key, item.Value)
+		}
+	}
+
+	if d := compiler.MapValueForKey(m, "discriminator"); d != nil {
+		e.discriminator(m, d, pointer+"/discriminator", &result)
+	}
+	if nullable && compiler.MapValueForKey(m, "type") == nil {
+		// without a type, null can only be allowed alongside the schema
+		return yaml.MapSlice{{Key: "anyOf", Value: []interface{}{result, yaml.MapSlice{{Key: "type", Value: "null"}}}}}
+	}
+	return result
+}
+
+// discriminator requires the discriminator property and adds an if/then
+// subschema for each of its values, selecting the variant it maps to, so
+// that a validator reports the errors of that variant alone. The values
+// are the mapping's, plus the names of the referenced variants it leaves
+// out. A discriminator without oneOf or anyOf, on the base schema of an
+// allOf hierarchy, only makes the property required, since selecting a
+// variant that includes the base would be circular.
+func (e *exporter) discriminator(schema yaml.MapSlice, d interface{}, pointer string, result *yaml.MapSlice) {
+	propertyName, _ := compiler.MapValueForKey(mapOf(d), "propertyName").(string)
+	if len(propertyName) == 0 {
+		return
+	}
+	setKey(result, "required", appendUnique(mapValueSlice(*result, "required"), propertyName))
+
+	variants, _ := compiler.MapValueForKey(schema, "oneOf").([]interface{})
+	if variants == nil {
+		variants, _ = compiler.MapValueForKey(schema, "anyOf").([]interface{})
+	}
+	if variants == nil {
+		return
+	}
+
+	var values []string
+	refs := map[string]string{}
+	for _, item := range mapOf(compiler.MapValueForKey(mapOf(d), "mapping")) {
+		ref := fmt.Sprint(item.Value)
+		if !strings.HasPrefix(ref, "#") {
+			// a bare name refers to a component schema
+			ref = componentSchemaPath(ref)
+		}
+		value := fmt.Sprint(item.Key)
+		values = append(values, value)
+		refs[value] = ref
+	}
+	for _, v := range variants {
+		ref, ok := compiler.MapValueForKey(mapOf(v), "$ref").(string)
+		if !ok {
+			e.warnf(pointer, "an inline variant can't be selected by the discriminator, so it is only checked by oneOf or anyOf")
+			continue
+		}
+		mapped := false
+		for _, r := range refs {
+			mapped = mapped || r == ref
+		}
+		if !mapped {
+			value := ref[strings.LastIndex(ref, "/")+1:]
+			values = append(values, value)
+			refs[value] = ref
+		}
+	}
+
+	enum := make([]interface{}, len(values))
+	for i, value := range values {
+		enum[i] = value
+	}
+	selectors := []interface{}{yaml.MapSlice{{Key: "properties", Value: yaml.MapSlice{{Key: propertyName, Value: yaml.MapSlice{{Key: "enum", Value: enum}}}}}}}
+	for _, value := range values {
+		selectors = append(selectors, yaml.MapSlice{
+			{Key: "if", Value: yaml.MapSlice{
+				{Key: "properties", Value: yaml.MapSlice{{Key: propertyName, Value: yaml.MapSlice{{Key: "const", Value: value}}}}},
+				{Key: "required", Value: []interface{}{propertyName}},
+			}},
+			{Key: "then", Value: yaml.MapSlice{{Key: "$ref", Value: e.ref(refs[value], pointer+jsonPointer("mapping", value))}}},
+		})
+	}
+	setKey(result, "allOf", append(mapValueSlice(*result, "allOf"), selectors...))
+}
+
+func setKey(m *yaml.MapSlice, key string, value interface{}) {
+	for i, item := range *m {
+		if fmt.Sprint(item.Key) == key {
+			(*m)[i].Value = value
+			return
+		}
+	}
+	*m = append(*m, yaml.MapItem{Key: key, Value: value})
+}
+
+func mapValueSlice(m yaml.MapSlice, key string) []interface{} {
+	values, _ := compiler.MapValueForKey(m, key).([]interface{})
+	return values
+}
+
+func appendUnique(values []interface{}, value interface{}) []interface{} {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func containsNil(values []interface{}) bool {
+	for _, v := range values {
+		if v == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ref points a reference into components/schemas at the exported schema.
+// Any other reference is kept, and reported, since it can't be resolved
+// outside of the OpenAPI document.
+func (e *exporter) ref(ref string, pointer string) string {
+	prefix := componentSchemaPath("")
+	if !strings.HasPrefix(ref, prefix) {
+		e.warnf(pointer, "%s is not a component schema, so it is left as it is", ref)
+		return ref
+	}
+
+	keys := refKeys(ref)[2:]
+	name, rest := keys[0], jsonPointer(keys[1:]...)
+	switch {
+	case e.bundle:
+		return "#" + jsonPointer("$defs", name) + rest
+	case name == e.current:
+		return "#" + rest
+	}
+	file := (&url.URL{Path: name + ".json"}).String()
+	if len(rest) > 0 {
+		return file + "#" + rest
+	}
+	return file
+}
+
+// property resolves a property's schema, following a reference to another
+// component.
+func (e *exporter) property(value interface{}) yaml.MapSlice {
+	m := mapOf(value)
+	if ref, ok := compiler.MapValueForKey(m, "$ref").(string); ok && strings.HasPrefix(ref, "#/") {
+		return mapOf(e.walker.sourceValue(refKeys(ref)...))
+	}
+	return m
+}
+
+// oneWay reports whether a property is readOnly or writeOnly.
+func (e *exporter) oneWay(value interface{}) bool {
+	m := e.property(value)
+	readOnly, _ := compiler.MapValueForKey(m, "readOnly").(bool)
+	writeOnly, _ := compiler.MapValueForKey(m, "writeOnly").(bool)
+	return readOnly || writeOnly
+}
+
+// omitted reports whether a property doesn't exist in the direction being
+// exported: a readOnly one in requests, or a writeOnly one in responses.
+func (e *exporter) omitted(value interface{}) bool {
+	m := e.property(value)
+	switch e.direction {
+	case "request":
+		readOnly, _ := compiler.MapValueForKey(m, "readOnly").(bool)
+		return readOnly
+	case "response":
+		writeOnly, _ := compiler.MapValueForKey(m, "writeOnly").(bool)
+		return writeOnly
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/googleapis/gnostic/compiler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONSchemas(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		// the document of S, without its $schema
+		expected    string
+		diagnostics []string
+	}{
+		{
+			name:        "nullable",
+			schema:      `{type: string, nullable: true, enum: [a, b]}`,
+			expected:    `{type: [string, "null"], enum: [a, b, null]}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "nullable without a type",
+			schema:      `{nullable: true, allOf: [{$ref: "#/components/schemas/T"}]}`,
+			expected:    `{anyOf: [{allOf: [{$ref: T.json}]}, {type: "null"}]}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "exclusive bounds",
+			schema:      `{type: integer, minimum: 0, exclusiveMinimum: true, maximum: 10}`,
+			expected:    `{type: integer, exclusiveMinimum: 0, maximum: 10}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "formats",
+			schema:      `{type: object, properties: {data: {type: string, format: byte}, file: {type: string, format: binary}, at: {type: string, format: date-time}}}`,
+			expected:    `{type: object, properties: {data: {type: string, contentEncoding: base64}, file: {type: string, contentMediaType: application/octet-stream}, at: {type: string, format: date-time}}}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "example and xml",
+			schema:      `{type: string, example: a, xml: {name: s}}`,
+			expected:    `{type: string, examples: [a]}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "read and write only",
+			schema:      `{type: object, required: [id, secret, name], properties: {id: {type: string, readOnly: true}, secret: {type: string, writeOnly: true}, name: {type: string}}}`,
+			expected:    `{type: object, required: [name], properties: {id: {type: string, readOnly: true}, secret: {type: string, writeOnly: true}, name: {type: string}}}`,
+			diagnostics: []string{},
+		},
+		{
+			name:   "discriminator",
+			schema: `{oneOf: [{$ref: "#/components/schemas/T"}, {$ref: "#/components/schemas/U"}], discriminator: {propertyName: kind, mapping: {t: "#/components/schemas/T"}}}`,
+			expected: `
+oneOf: [{$ref: T.json}, {$ref: U.json}]
+required: [kind]
+allOf:
+	- {properties: {kind: {enum: [t, U]}}}
+	- {if: {properties: {kind: {const: t}}, required: [kind]}, then: {$ref: T.json}}
+	- {if: {properties: {kind: {const: U}}, required: [kind]}, then: {$ref: U.json}}
+`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "references",
+			schema:      `{type: object, properties: {t: {$ref: "#/components/schemas/T"}, ts: {type: array, items: {$ref: "#/components/schemas/T"}}}}`,
+			expected:    `{type: object, properties: {t: {$ref: T.json}, ts: {type: array, items: {$ref: T.json}}}}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "reference outside of the schemas",
+			schema:      `{type: object, properties: {t: {$ref: "#/components/schemas/T"}}, additionalProperties: {$ref: "#/components/responses/R"}}`,
+			expected:    `{type: object, properties: {t: {$ref: T.json}}, additionalProperties: {$ref: "#/components/responses/R"}}`,
+			diagnostics: []string{"warning /components/schemas/S/additionalProperties/$ref"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := walk(t, fmt.Sprintf(`
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths: {}
+components:
+	schemas:
+		S: %s
+		T: {type: object, properties: {kind: {type: string}}}
+		U: {type: object, properties: {kind: {type: string}}}
+`, c.schema))
+
+			documents, diags := w.JSONSchemas("")
+			expected, _ := readSpec(t, c.expected)
+			assertSameYAML(t, withDialect(expected), compiler.MapValueForKey(documents, "S"))
+			assert.Equal(t, c.diagnostics, diagnosticsOf(diags))
+		})
+	}
+}
+
+func TestJSONSchemaBundle(t *testing.T) {
+	w := walk(t, `
+openapi: 3.0.0
+info: {title: Pets, version: "1"}
+paths: {}
+components:
+	schemas:
+		Pet:
+			type: object
+			required: [id, name, password]
+			properties:
+				id: {type: integer, readOnly: true}
+				name: {type: string}
+				password: {type: string, writeOnly: true}
+				owner: {$ref: "#/components/schemas/Owner"}
+		Owner: {type: object, properties: {name: {type: string}}}
+`)
+
+	cases := []struct {
+		direction string
+		// the Pet schema
+		expected string
+	}{
+		{
+			direction: "",
+			expected:  `{type: object, required: [name], properties: {id: {type: integer, readOnly: true}, name: {type: string}, password: {type: string, writeOnly: true}, owner: {$ref: "#/$defs/Owner"}}}`,
+		},
+		{
+			direction: "request",
+			expected:  `{type: object, required: [name, password], properties: {name: {type: string}, password: {type: string, writeOnly: true}, owner: {$ref: "#/$defs/Owner"}}}`,
+		},
+		{
+			direction: "response",
+			expected:  `{type: object, required: [id, name], properties: {id: {type: integer, readOnly: true}, name: {type: string}, owner: {$ref: "#/$defs/Owner"}}}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.direction, func(t *testing.T) {
+			bundle, diags := w.JSONSchemaBundle(c.direction)
+			assert.Empty(t, diags)
+			assertYAML(t, fmt.Sprintf(`
+$schema: %s
+title: Pets
+$defs:
+	Pet: %s
+	Owner: {type: object, properties: {name: {type: string}}}
+`, JSONSchemaDialect, c.expected), bundle)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"strconv"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Locate fills in the File, Line and Column of each diagnostic from the
+// document's source, which may be YAML or JSON. A pointer to a value that
+// isn't in the source, e.g. a missing operationId, is placed at its closest
+// ancestor that is.
+func Locate(diags []Diagnostic, file string, data []byte) error {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &root); err != nil {
+		return err
+	}
+
+	positions := map[string]*yamlv3.Node{}
+	if len(root.Content) > 0 {
+		indexPositions(root.Content[0], "", positions, 0)
+	}
+
+	for i := range diags {
+		diags[i].File = file
+		pointer := diags[i].Pointer
+		for {
+			if node, ok := positions[pointer]; ok {
+				diags[i].Line = node.Line
+				diags[i].Column = node.Column
+				break
+			}
+			if len(pointer) == 0 {
+				break
+			}
+			pointer = parentPointer(pointer)
+		}
+	}
+	return nil
+}
+
+// indexPositions records the node for each pointer in the document. Members
+// of a mapping are located at their key, the rest at the value itself.
+func indexPositions(node *yamlv3.Node, pointer string, positions map[string]*yamlv3.Node, depth int) {
+	if depth > 64 {
+		// a cycle of YAML aliases
+		return
+	}
+	if _, ok := positions[pointer]; !ok {
+		positions[pointer] = node
+	}
+
+	switch node.Kind {
+	case yamlv3.AliasNode:
+		indexPositions(node.Alias, pointer, positions, depth+1)
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			child := pointer + jsonPointer(key.Value)
+			positions[child] = key
+			indexPositions(value, child, positions, depth+1)
+		}
+	case yamlv3.SequenceNode:
+		for i, item := range node.Content {
+			indexPositions(item, pointer+jsonPointer(strconv.Itoa(i)), positions, depth+1)
+		}
+	}
+}
+
+func parentPointer(pointer string) string {
+	for i := len(pointer) - 1; i >= 0; i-- {
+		if pointer[i] == '/' {
+			return pointer[:i]
+		}
+	}
+	return ""
+}
@@ -1,5 +1,7 @@
 package parser
 
+import "strings"
+
 type Component struct {
 	componentName string
 }
@@ -22,6 +24,9 @@ type SchemaModel interface {
 	IsComponent() bool
 	GetComponentName() string
 	GetType() string
+	GetDescription() string
+	IsDeprecated() bool
+	GetXML() *XML
 	IsPrimitive() bool
 	IsArray() bool
 	IsObject() bool
@@ -36,15 +41,49 @@ type Discriminator struct {
 
 type CommonSchemaModel struct {
 	Component
-	Title    string
-	Type     string
-	Nullable bool
+	Title       string
+	Description string
+	Type        string
+	Nullable    bool
+	Deprecated  bool
+	// Enum lists the allowed values, as decoded from YAML, or is empty if
+	// any value of the type is allowed.
+	Enum []interface{}
+	// XML is nil if the schema has no xml object.
+	XML *XML
+}
+
+// XML is the xml object of a schema, which adjusts how it is serialized as
+// XML.
+type XML struct {
+	Name      string
+	Namespace string
+	Prefix    string
+	// Attribute properties are serialized as attributes, not elements.
+	Attribute bool
+	// Wrapped arrays are enclosed in an element of their own.
+	Wrapped bool
 }
 
 func (m *CommonSchemaModel) GetType() string {
 	return m.Type
 }
 
+func (m *CommonSchemaModel) GetDescription() string {
+	if len(m.Description) == 0 {
+		return m.Title
+	}
+	return m.Description
+}
+
+func (m *CommonSchemaModel) IsDeprecated() bool {
+	return m.Deprecated
+}
+
+func (m *CommonSchemaModel) GetXML() *XML {
+	return m.XML
+}
+
 func (m *CommonSchemaModel) IsPrimitive() bool {
 	return m.Type != "object" && m.Type != "array"
 }
@@ -102,32 +141,119 @@ type PrimitiveSchemaModel struct {
 }
 
 type Operation struct {
-	Name       string
-	Requests   []Request
-	Method     string
-	Path       string
-	Responses  []Response
-	Parameters []Parameter
+	OperationID string
+	Name        string
+	Summary     string
+	Description string
+	Deprecated  bool
+	Tags        []string
+	Security    []SecurityRequirement
+	Requests    []Request
+	Method      string
+	Path        string
+	Responses   []Response
+	Parameters  []Parameter
 }
 
 type Request struct {
 	Component
 	Accept string
 	Body   SchemaModel
+	// Encoding describes how properties of a form body are serialized, in
+	// the order they appear in the spec.
+	Encoding []Encoding
+}
+
+// IsForm reports whether the request body is multipart/form-data or
+// application/x-www-form-urlencoded.
+func (r Request) IsForm() bool {
+	return IsFormMediaType(r.Accept)
+}
+
+// IsFormMediaType reports whether mediaType is one of the form media types.
+func IsFormMediaType(mediaType string) bool {
+	switch strings.ToLower(mediaType) {
+	case "multipart/form-data", "application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
+
+// Encoding is the encoding object of one property of a form body.
+type Encoding struct {
+	// Property is the name of the property it applies to.
+	Property string
+	// ContentType of a multipart part, e.g. image/png. Empty means the
+	// default for the property's type.
+	ContentType string
+	// Headers are the extra headers of a multipart part.
+	Headers []Header
+	// Style, Explode and AllowReserved apply to urlencoded bodies, with the
+	// same defaults as query parameters.
+	Style         string
+	Explode       bool
+	AllowReserved bool
 }
 
 type Parameter struct {
 	Component
-	Name     string
-	In       string
-	Required bool
-	Schema   SchemaModel
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Deprecated  bool
+	// Style is how the value is serialized, defaulted from In if the spec
+	// doesn't give one.
+	Style string
+	// Explode defaults to true for the form style, false otherwise.
+	Explode bool
+	// AllowReserved means reserved characters in query values aren't
+	// percent-encoded.
+	AllowReserved bool
+	Schema        SchemaModel
 }
 
 type Response struct {
 	Component
 	StatusCode  string
+	Description string
 	ContentType string
 	Body        SchemaModel
-	Headers     map[string]SchemaModel
+	Headers     []Header
+}
+
+type Header struct {
+	Component
+	Name        string
+	Description string
+	Required    bool
+	Deprecated  bool
+	Schema      SchemaModel
+}
+
+// SecurityRequirement maps security scheme names to the scopes required of
+// each. All schemes in a requirement must be satisfied; an operation's
+// requirements are alternatives, any one of which is enough.
+type SecurityRequirement map[string][]string
+
+// SecurityScheme is an entry in components.securitySchemes.
+type SecurityScheme struct {
+	// Name is the scheme's key in components.securitySchemes, which is what
+	// security requirements refer to.
+	Name        string
+	Type        string // apiKey | http | oauth2 | openIdConnect
+	Description string
+
+	// ParamName and In locate the key of an apiKey scheme; In is one of
+	// header, query or cookie.
+	ParamName string
+	In        string
+
+	// Scheme is the http auth scheme, e.g. basic or bearer.
+	Scheme       string
+	BearerFormat string
+
+	// Scopes available across all oauth2 flows, with their descriptions.
+	Scopes           map[string]string
+	OpenIDConnectURL string
 }
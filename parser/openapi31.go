@@ -0,0 +1,425 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/googleapis/gnostic/compiler"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/utils"
+)
+
+// gnostic only reads OpenAPI 3.0, so a 3.1 document is rewritten into its
+// 3.0 equivalent before it is parsed, e.g. `type: [string, "null"]` becomes
+// `type: string` with `nullable: true`. JSON Schema 2020-12 keywords with no
+// 3.0 equivalent are dropped and reported.
+
+// unsupportedKeywords are the JSON Schema 2020-12 keywords that can't be
+// expressed in OpenAPI 3.0.
+var unsupportedKeywords = map[string]bool{
+	"if": true, "then": true, "else": true,
+	"dependentSchemas": true, "dependentRequired": true,
+	"unevaluatedProperties": true, "unevaluatedItems": true,
+	"patternProperties": true, "propertyNames": true,
+	"contains": true, "minContains": true, "maxContains": true,
+	"$id": true, "$schema": true, "$anchor": true, "$vocabulary": true,
+	"$dynamicRef": true, "$dynamicAnchor": true,
+}
+
+// IsOpenAPI31 reports whether the raw document, as returned by
+// compiler.ReadInfoFromBytes, declares OpenAPI 3.1.
+func IsOpenAPI31(info interface{}) bool {
+	m, _ := compiler.UnpackMap(info)
+	version, _ := compiler.MapValueForKey(m, "openapi").(string)
+	return strings.HasPrefix(version, "3.1")
+}
+
+// DowngradeOpenAPI31 rewrites a 3.1 document into OpenAPI 3.0, returning
+// diagnostics for everything that couldn't be carried over. Schemas in
+// $defs are moved to components/schemas, and references to them rewritten.
+func DowngradeOpenAPI31(info interface{}) (yaml.MapSlice, []Diagnostic) {
+	d := &downgrader{refs: map[string]string{}, names: map[string]bool{}}
+
+	root, _ := compiler.UnpackMap(info)
+	for _, name := range mapKeys(compiler.MapValueForKey(mapOf(compiler.MapValueForKey(root, "components")), "schemas")) {
+		d.names[name] = true
+	}
+
+	doc := yaml.MapSlice{}
+	hasPaths := false
+	for _, item := range root {
+		key := fmt.Sprint(item.Key)
+		pointer := jsonPointer(key)
+		switch key {
+		case "openapi":
+			doc = append(doc, yaml.MapItem{Key: key, Value: "3.0.3"})
+		case "jsonSchemaDialect":
+			if dialect, _ := item.Value.(string); dialect != "https://spec.openapis.org/oas/3.1/dialect/base" {
+				d.warnf(pointer, "jsonSchemaDialect %s is ignored; schemas are read as the OpenAPI dialect", dialect)
+			}
+		case "webhooks":
+			d.warnf(pointer, "webhooks are not generated")
+		case "info":
+			doc = append(doc, yaml.MapItem{Key: key, Value: downgradeInfo(item.Value)})
+		case "paths":
+			hasPaths = true
+			doc = append(doc, yaml.MapItem{Key: key, Value: d.document(item.Value, pointer)})
+		case "components":
+			components := yaml.MapSlice{}
+			for _, c := range mapOf(item.Value) {
+				name := fmt.Sprint(c.Key)
+				if name == "pathItems" {
+					d.warnf(pointer+"/pathItems", "components.pathItems are not supported")
+					continue
+				}
+				if name == "schemas" {
+					schemas := yaml.MapSlice{}
+					for _, schema := range mapOf(c.Value) {
+						schemaName := fmt.Sprint(schema.Key)
+						schemas = append(schemas, yaml.MapItem{Key: schema.Key, Value: d.schema(schema.Value, jsonPointer("components", "schemas", schemaName), schemaName)})
+					}
+					components = append(components, yaml.MapItem{Key: c.Key, Value: schemas})
+					continue
+				}
+				components = append(components, yaml.MapItem{Key: c.Key, Value: d.document(c.Value, pointer+jsonPointer(name))})
+			}
+			doc = append(doc, yaml.MapItem{Key: key, Value: components})
+		default:
+			doc = append(doc, yaml.MapItem{Key: key, Value: d.document(item.Value, pointer)})
+		}
+	}
+	if !hasPaths {
+		// paths are optional in 3.1
+		doc = append(doc, yaml.MapItem{Key: "paths", Value: yaml.MapSlice{}})
+	}
+
+	if len(d.defs) > 0 {
+		doc = withHoistedDefs(doc, d.defs)
+	}
+	return d.rewriteRefs(doc).(yaml.MapSlice), d.diags
+}
+
+type downgrader struct {
+	diags []Diagnostic
+	// defs are the schemas hoisted out of $defs, by their new names.
+	defs yaml.MapSlice
+	// refs maps the pointers of hoisted schemas to their new references.
+	refs map[string]string
+	// names are the component schema names taken so far.
+	names map[string]bool
+}
+
+func (d *downgrader) warnf(pointer string, format string, args ...interface{}) {
+	d.diags = append(d.diags, Diagnostic{Severity: SeverityWarning, Code: "unsupported-keyword", Message: fmt.Sprintf(format, args...), Pointer: pointer})
+}
+
+func (d *downgrader) errorf(pointer string, format string, args ...interface{}) {
+	d.diags = append(d.diags, Diagnostic{Severity: SeverityError, Code: "unsupported-keyword", Message: fmt.Sprintf(format, args...), Pointer: pointer})
+}
+
+func mapKeys(value interface{}) []string {
+	keys, _ := entries(value)
+	return keys
+}
+
+// downgradeInfo drops the fields of the info object that 3.0 doesn't have.
+func downgradeInfo(value interface{}) interface{} {
+	info := yaml.MapSlice{}
+	for _, item := range mapOf(value) {
+		switch fmt.Sprint(item.Key) {
+		case "summary":
+			continue
+		case "license":
+			license := yaml.MapSlice{}
+			for _, l := range mapOf(item.Value) {
+				if fmt.Sprint(l.Key) != "identifier" {
+					license = append(license, l)
+				}
+			}
+			item.Value = license
+		}
+		info = append(info, item)
+	}
+	return info
+}
+
+// document rewrites the part of the document outside of schemas, looking
+// for the schemas within it.
+func (d *downgrader) document(value interface{}, pointer string) interface{} {
+	switch value := value.(type) {
+	case yaml.MapSlice:
+		result := yaml.MapSlice{}
+		if ref := compiler.MapValueForKey(value, "$ref"); ref != nil {
+			// 3.1 allows a summary and description alongside a reference
+			return yaml.MapSlice{{Key: "$ref", Value: ref}}
+		}
+		for _, item := range value {
+			key := fmt.Sprint(item.Key)
+			child := pointer + jsonPointer(key)
+			switch {
+			case key == "schema":
+				item.Value = d.schema(item.Value, child, "")
+			case key == "example" || key == "examples" || key == "default" || strings.HasPrefix(key, "x-"):
+				// values, not part of the document's structure
+			default:
+				item.Value = d.document(item.Value, child)
+			}
+			result = append(result, item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = d.document(item, fmt.Sprintf("%s/%d", pointer, i))
+		}
+		return result
+	}
+	return value
+}
+
+// schema rewrites a JSON Schema 2020-12 schema as an OpenAPI 3.0 schema.
+// owner is the name of the component the schema belongs to, if any, which
+// hoisted $defs are named after when their own names are taken.
+func (d *downgrader) schema(value interface{}, pointer string, owner string) interface{} {
+	if b, ok := value.(bool); ok {
+		if !b {
+			d.warnf(pointer, "the false schema is not supported; any value is allowed")
+		}
+		return yaml.MapSlice{}
+	}
+
+	m, ok := compiler.UnpackMap(value)
+	if !ok {
+		return value
+	}
+	if ref := compiler.MapValueForKey(m, "$ref"); ref != nil {
+		for _, item := range m {
+			if key := fmt.Sprint(item.Key); key != "$ref" && key != "description" && key != "summary" && key != "$comment" {
+				d.warnf(pointer+jsonPointer(key), "%s alongside $ref is ignored", key)
+			}
+		}
+		return yaml.MapSlice{{Key: "$ref", Value: ref}}
+	}
+
+	result := yaml.MapSlice{}
+	set := func(key string, value interface{}) {
+		for i, item := range result {
+			if fmt.Sprint(item.Key) == key {
+				result[i].Value = value
+				return
+			}
+		}
+		result = append(result, yaml.MapItem{Key: key, Value: value})
+	}
+	has := func(key string) bool {
+		return compiler.MapValueForKey(m, key) != nil
+	}
+
+	for _, item := range m {
+		key := fmt.Sprint(item.Key)
+		child := pointer + jsonPointer(key)
+		switch {
+		case key == "type":
+			d.schemaType(item.Value, child, set)
+		case key == "const":
+			set("enum", []interface{}{item.Value})
+			if !has("type") {
+				d.constType(item.Value, child, set)
+			}
+		case key == "examples":
+			if examples, ok := item.Value.([]interface{}); ok && len(examples) > 0 && !has("example") {
+				set("example", examples[0])
+			}
+		case key == "exclusiveMinimum" || key == "exclusiveMaximum":
+			if _, isBool := item.Value.(bool); isBool {
+				set(key, item.Value)
+				continue
+			}
+			bound := "minimum"
+			if key == "exclusiveMaximum" {
+				bound = "maximum"
+			}
+			set(bound, item.Value)
+			set(key, true)
+		case key == "$defs":
+			d.hoist(item.Value, child, owner)
+		case key == "prefixItems":
+			d.prefixItems(item.Value, child, has("items"), set)
+		case key == "contentEncoding":
+			if item.Value == "base64" && !has("format") {
+				set("format", "byte")
+			}
+		case key == "contentMediaType":
+			if !has("format") && !has("contentEncoding") {
+				set("format", "binary")
+			}
+		case key == "$comment":
+		case unsupportedKeywords[key]:
+			d.warnf(child, "%s is not supported and is ignored", key)
+		case key == "properties":
+			properties := yaml.MapSlice{}
+			for _, p := range mapOf(item.Value) {
+				properties = append(properties, yaml.MapItem{Key: p.Key, Value: d.schema(p.Value, child+jsonPointer(fmt.Sprint(p.Key)), owner)})
+			}
+			set(key, properties)
+		case key == "items" || key == "not":
+			set(key, d.schema(item.Value, child, owner))
+		case key == "additionalProperties":
+			if _, isBool := item.Value.(bool); isBool {
+				set(key, item.Value)
+			} else {
+				set(key, d.schema(item.Value, child, owner))
+			}
+		case key == "allOf" || key == "anyOf" || key == "oneOf":
+			subschemas, _ := item.Value.([]interface{})
+			converted := make([]interface{}, len(subschemas))
+			for i, s := range subschemas {
+				converted[i] = d.schema(s, fmt.Sprintf("%s/%d", child, i), owner)
+			}
+			set(key, converted)
+		default:
+			set(key, item.Value)
+		}
+	}
+	return result
+}
+
+// constType sets the type of a schema that has a const but no type, which
+// the generator needs to declare it, from the const's value.
+func (d *downgrader) constType(value interface{}, pointer string, set func(string, interface{})) {
+	switch value.(type) {
+	case string:
+		set("type", "string")
+	case bool:
+		set("type", "boolean")
+	case int, int64, uint64:
+		set("type", "integer")
+	case float64:
+		set("type", "number")
+	default:
+		d.errorf(pointer, "the type of a null, object or array const can't be inferred; add a type")
+	}
+}
+
+// schemaType converts a type, which may be an array of types including
+// "null", into a single type and nullable.
+func (d *downgrader) schemaType(value interface{}, pointer string, set func(string, interface{})) {
+	var types []string
+	switch value := value.(type) {
+	case string:
+		types = []string{value}
+	case []interface{}:
+		types = compiler.ConvertInterfaceArrayToStringArray(value)
+	}
+
+	var nonNull []string
+	for _, t := range types {
+		if t == "null" {
+			set("nullable", true)
+		} else {
+			nonNull = append(nonNull, t)
+		}
+	}
+
+	switch len(nonNull) {
+	case 0:
+		d.warnf(pointer, "a schema that only allows null is not supported; any value is allowed")
+	case 1:
+		set("type", nonNull[0])
+	default:
+		d.errorf(pointer, "multiple types are not supported; only %s is generated", nonNull[0])
+		set("type", nonNull[0])
+	}
+}
+
+// prefixItems converts a tuple into an array of its item schema, which can
+// only be done when every position has the same schema.
+func (d *downgrader) prefixItems(value interface{}, pointer string, hasItems bool, set func(string, interface{})) {
+	items, _ := value.([]interface{})
+	if len(items) == 0 || hasItems {
+		d.warnf(pointer, "prefixItems is not supported and is ignored")
+		return
+	}
+
+	for _, item := range items[1:] {
+		if !reflect.DeepEqual(item, items[0]) {
+			d.errorf(pointer, "prefixItems with different schemas are not supported; the first is used for every item")
+			break
+		}
+	}
+	set("items", d.schema(items[0], pointer+"/0", ""))
+}
+
+// hoist moves the schemas of a $defs to components/schemas. They keep their
+// names unless those are taken, in which case the owning component's name
+// is prefixed.
+func (d *downgrader) hoist(value interface{}, pointer string, owner string) {
+	for _, item := range mapOf(value) {
+		key := fmt.Sprint(item.Key)
+		name := key
+		if d.names[name] {
+			name = utils.ToPascalCase(owner) + utils.ToPascalCase(key)
+		}
+		for i := 2; d.names[name]; i++ {
+			name = fmt.Sprintf("%s%d", utils.ToPascalCase(owner)+utils.ToPascalCase(key), i)
+		}
+		d.names[name] = true
+
+		child := pointer + jsonPointer(key)
+		d.refs["#"+child] = "#" + jsonPointer("components", "schemas", name)
+		d.defs = append(d.defs, yaml.MapItem{Key: name, Value: d.schema(item.Value, child, name)})
+	}
+}
+
+func withHoistedDefs(doc yaml.MapSlice, defs yaml.MapSlice) yaml.MapSlice {
+	for i, item := range doc {
+		if fmt.Sprint(item.Key) != "components" {
+			continue
+		}
+		components := mapOf(item.Value)
+		for j, c := range components {
+			if fmt.Sprint(c.Key) == "schemas" {
+				components[j].Value = append(mapOf(c.Value), defs...)
+				return doc
+			}
+		}
+		doc[i].Value = append(components, yaml.MapItem{Key: "schemas", Value: defs})
+		return doc
+	}
+	return append(doc, yaml.MapItem{Key: "components", Value: yaml.MapSlice{{Key: "schemas", Value: defs}}})
+}
+
+// rewriteRefs points references into hoisted $defs at their new location.
+func (d *downgrader) rewriteRefs(value interface{}) interface{} {
+	switch value := value.(type) {
+	case yaml.MapSlice:
+		for i, item := range value {
+			if ref, ok := item.Value.(string); ok && fmt.Sprint(item.Key) == "$ref" {
+				value[i].Value = d.rewriteRef(ref)
+			} else {
+				value[i].Value = d.rewriteRefs(item.Value)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = d.rewriteRefs(item)
+		}
+	}
+	return value
+}
+
+func (d *downgrader) rewriteRef(ref string) string {
+	// the longest match wins, for $defs nested in $defs
+	match := ""
+	for old := range d.refs {
+		if (ref == old || strings.HasPrefix(ref, old+"/")) && len(old) > len(match) {
+			match = old
+		}
+	}
+	if len(match) == 0 {
+		return ref
+	}
+	return d.refs[match] + strings.TrimPrefix(ref, match)
+}
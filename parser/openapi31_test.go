@@ -0,0 +1,213 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// assertYAML compares a rewritten document with the YAML it should be.
+func assertYAML(t *testing.T, expected string, actual interface{}) {
+	want, _ := readSpec(t, expected)
+	assertSameYAML(t, want, actual)
+}
+
+// assertSameYAML compares two documents as YAML, so that the diff of a
+// failure is readable.
+func assertSameYAML(t *testing.T, expected interface{}, actual interface{}) {
+	expectedYAML, err := yaml.Marshal(expected)
+	require.NoError(t, err)
+	actualYAML, err := yaml.Marshal(actual)
+	require.NoError(t, err)
+	assert.Equal(t, string(expectedYAML), string(actualYAML))
+}
+
+// diagnosticsOf lists the severity and pointer of each diagnostic.
+func diagnosticsOf(diags []Diagnostic) []string {
+	actual := []string{}
+	for _, d := range diags {
+		actual = append(actual, fmt.Sprintf("%s %s", d.Severity, d.Pointer))
+	}
+	return actual
+}
+
+func TestDowngradeOpenAPI31Schema(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		// the 3.0 schema
+		expected    string
+		diagnostics []string
+	}{
+		{
+			name:        "nullable",
+			schema:      `{type: [string, "null"], maxLength: 3}`,
+			expected:    `{nullable: true, type: string, maxLength: 3}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "const with a type",
+			schema:      `{type: string, const: fixed}`,
+			expected:    `{type: string, enum: [fixed]}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "string const",
+			schema:      `{const: fixed}`,
+			expected:    `{enum: [fixed], type: string}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "integer const",
+			schema:      `{const: 3}`,
+			expected:    `{enum: [3], type: integer}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "number const",
+			schema:      `{const: 1.5}`,
+			expected:    `{enum: [1.5], type: number}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "boolean const",
+			schema:      `{const: true}`,
+			expected:    `{enum: [true], type: boolean}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "object const",
+			schema:      `{const: {a: 1}}`,
+			expected:    `{enum: [{a: 1}]}`,
+			diagnostics: []string{"error /components/schemas/S/const"},
+		},
+		{
+			name:        "exclusive bounds",
+			schema:      `{type: integer, exclusiveMinimum: 0, exclusiveMaximum: 10}`,
+			expected:    `{type: integer, minimum: 0, exclusiveMinimum: true, maximum: 10, exclusiveMaximum: true}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "examples",
+			schema:      `{type: string, examples: [a, b]}`,
+			expected:    `{type: string, example: a}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "content encoding",
+			schema:      `{type: string, contentEncoding: base64}`,
+			expected:    `{type: string, format: byte}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "tuple of one schema",
+			schema:      `{type: array, prefixItems: [{type: string}, {type: string}]}`,
+			expected:    `{type: array, items: {type: string}}`,
+			diagnostics: []string{},
+		},
+		{
+			name:        "tuple of several schemas",
+			schema:      `{type: array, prefixItems: [{type: string}, {type: integer}]}`,
+			expected:    `{type: array, items: {type: string}}`,
+			diagnostics: []string{"error /components/schemas/S/prefixItems"},
+		},
+		{
+			name:        "multiple types",
+			schema:      `{type: [string, integer]}`,
+			expected:    `{type: string}`,
+			diagnostics: []string{"error /components/schemas/S/type"},
+		},
+		{
+			name:        "unsupported keywords",
+			schema:      `{type: object, properties: {a: {type: string, $comment: note}}, patternProperties: {"^x": {type: string}}, if: {required: [a]}}`,
+			expected:    `{type: object, properties: {a: {type: string}}}`,
+			diagnostics: []string{"warning /components/schemas/S/patternProperties", "warning /components/schemas/S/if"},
+		},
+		{
+			name:        "ref with siblings",
+			schema:      `{$ref: "#/components/schemas/T", description: d, default: x}`,
+			expected:    `{$ref: "#/components/schemas/T"}`,
+			diagnostics: []string{"warning /components/schemas/S/default"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			info, _ := readSpec(t, fmt.Sprintf(`
+openapi: 3.1.0
+info: {title: t, version: "1"}
+components:
+	schemas:
+		S: %s
+`, c.schema))
+			require.True(t, IsOpenAPI31(info))
+
+			doc, diags := DowngradeOpenAPI31(info)
+			assertYAML(t, fmt.Sprintf(`
+openapi: 3.0.3
+info: {title: t, version: "1"}
+components:
+	schemas:
+		S: %s
+paths: {}
+`, c.expected), doc)
+			assert.Equal(t, c.diagnostics, diagnosticsOf(diags))
+		})
+	}
+}
+
+func TestDowngradeOpenAPI31Document(t *testing.T) {
+	info, _ := readSpec(t, `
+openapi: 3.1.0
+info: {title: t, version: "1", summary: s, license: {name: MIT, identifier: MIT}}
+webhooks:
+	newItem: {post: {responses: {"200": {description: ok}}}}
+paths:
+	/items:
+		get:
+			operationId: listItems
+			responses:
+				"200":
+					description: ok
+					content:
+						application/json:
+							schema: {type: array, items: {$ref: "#/components/schemas/Item/$defs/Tag"}}
+components:
+	schemas:
+		Tag: {type: string}
+		Item:
+			type: object
+			properties:
+				tag: {$ref: "#/components/schemas/Item/$defs/Tag"}
+			$defs:
+				Tag: {type: object, properties: {name: {type: string}}}
+`)
+
+	doc, diags := DowngradeOpenAPI31(info)
+	assertYAML(t, `
+openapi: 3.0.3
+info: {title: t, version: "1", license: {name: MIT}}
+paths:
+	/items:
+		get:
+			operationId: listItems
+			responses:
+				"200":
+					description: ok
+					content:
+						application/json:
+							schema: {type: array, items: {$ref: "#/components/schemas/ItemTag"}}
+components:
+	schemas:
+		Tag: {type: string}
+		Item:
+			type: object
+			properties:
+				tag: {$ref: "#/components/schemas/ItemTag"}
+		ItemTag: {type: object, properties: {name: {type: string}}}
+`, doc)
+	assert.Equal(t, []string{"warning /webhooks"}, diagnosticsOf(diags))
+}
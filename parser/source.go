@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/googleapis/gnostic/compiler"
+)
+
+// gnostic doesn't model everything in the spec (e.g. security requirements
+// come through as empty structs), so the walker can also be given the raw
+// document to read those parts from.
+
+// SetSource gives the walker the raw document, as returned by
+// compiler.ReadInfoFromBytes. Traverse fails without it if the spec has
+// security schemes.
+func (o *Walker) SetSource(info interface{}) {
+	if m, ok := compiler.UnpackMap(info); ok {
+		o.source = m
+	}
+}
+
+// sourceValue looks up a value in the raw document by its path of map keys,
+// returning nil if any part of the path is missing. Keys that YAML reads as
+// something other than a string, like the status codes of responses, are
+// matched by their string form.
+func (o *Walker) sourceValue(keys ...string) interface{} {
+	var value interface{} = o.source
+	for _, key := range keys {
+		m, ok := compiler.UnpackMap(value)
+		if !ok {
+			return nil
+		}
+		value = nil
+		for _, item := range m {
+			if fmt.Sprint(item.Key) == key {
+				value = item.Value
+				break
+			}
+		}
+	}
+	return value
+}
+
+// securityRequirements reads the `security` array for an operation, falling
+// back to the document level requirements. An explicitly empty array is
+// returned as an empty, non-nil slice, meaning no security is required.
+func (o *Walker) securityRequirements(path string, method string) []SecurityRequirement {
+	raw := o.sourceValue("paths", path, strings.ToLower(method), "security")
+	if raw == nil {
+		raw = o.sourceValue("security")
+	}
+
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	requirements := []SecurityRequirement{}
+	for _, item := range items {
+		m, ok := compiler.UnpackMap(item)
+		if !ok {
+			continue
+		}
+
+		requirement := SecurityRequirement{}
+		for _, kv := range m {
+			name, _ := kv.Key.(string)
+			scopes := []string{}
+			if values, ok := kv.Value.([]interface{}); ok {
+				scopes = compiler.ConvertInterfaceArrayToStringArray(values)
+			}
+			requirement[name] = scopes
+		}
+		requirements = append(requirements, requirement)
+	}
+	return requirements
+}
+
+// parameterExplode reads `explode` for the index'th parameter of an
+// operation, reporting whether it was given at all.
+func (o *Walker) parameterExplode(path string, method string, index int) (bool, bool) {
+	items, ok := o.sourceValue("paths", path, strings.ToLower(method), "parameters").([]interface{})
+	if !ok || index >= len(items) {
+		return false, false
+	}
+
+	m, ok := compiler.UnpackMap(items[index])
+	if !ok {
+		return false, false
+	}
+	explode, ok := compiler.MapValueForKey(m, "explode").(bool)
+	return explode, ok
+}
+
+// encodingExplode reads `explode` from the encoding object of a property of
+// an operation's request body, reporting whether it was given at all.
+func (o *Walker) encodingExplode(path string, method string, mediaType string, property string) (bool, bool) {
+	explode, ok := o.sourceValue("paths", path, strings.ToLower(method), "requestBody", "content", mediaType, "encoding", property, "explode").(bool)
+	return explode, ok
+}
@@ -0,0 +1,554 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/googleapis/gnostic/compiler"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Swagger 2.0 documents are converted into OpenAPI 3.0 before they are
+// parsed, so that they go through the same walker and generator. Parameter
+// and response references are inlined, and path level parameters copied
+// into each operation, since the walker only reads them there.
+
+// parameterFields are the fields of a non-body Swagger parameter, and of its
+// items, that describe its value and so belong in its schema.
+var parameterFields = []string{
+	"type", "format", "enum", "default", "maximum", "exclusiveMaximum",
+	"minimum", "exclusiveMinimum", "maxLength", "minLength", "pattern",
+	"maxItems", "minItems", "uniqueItems", "multipleOf",
+}
+
+// IsSwagger2 reports whether the raw document, as returned by
+// compiler.ReadInfoFromBytes, is a Swagger 2.0 document.
+func IsSwagger2(info interface{}) bool {
+	m, _ := compiler.UnpackMap(info)
+	version := compiler.MapValueForKey(m, "swagger")
+	return fmt.Sprint(version) == "2.0" || fmt.Sprint(version) == "2"
+}
+
+// ConvertSwagger2 converts a Swagger 2.0 document into OpenAPI 3.0,
+// returning diagnostics for anything that couldn't be carried over.
+func ConvertSwagger2(info interface{}) (yaml.MapSlice, []Diagnostic) {
+	root, _ := compiler.UnpackMap(info)
+	c := &swaggerConverter{root: root}
+
+	doc := yaml.MapSlice{{Key: "openapi", Value: "3.0.3"}}
+	for _, item := range root {
+		key := fmt.Sprint(item.Key)
+		switch {
+		case key == "info" || key == "tags" || key == "externalDocs" || key == "security" || strings.HasPrefix(key, "x-"):
+			doc = append(doc, item)
+		}
+	}
+	if servers := c.servers(); len(servers) > 0 {
+		doc = append(doc, yaml.MapItem{Key: "servers", Value: servers})
+	}
+	doc = append(doc, yaml.MapItem{Key: "paths", Value: c.paths()})
+	if components := c.components(); len(components) > 0 {
+		doc = append(doc, yaml.MapItem{Key: "components", Value: components})
+	}
+
+	return rewriteSwaggerRefs(doc).(yaml.MapSlice), c.diags
+}
+
+type swaggerConverter struct {
+	root  yaml.MapSlice
+	diags []Diagnostic
+}
+
+func (c *swaggerConverter) warnf(pointer string, format string, args ...interface{}) {
+	c.diags = append(c.diags, Diagnostic{Severity: SeverityWarning, Code: "swagger-conversion", Message: fmt.Sprintf(format, args...), Pointer: pointer})
+}
+
+func (c *swaggerConverter) value(keys ...string) interface{} {
+	w := Walker{source: c.root}
+	return w.sourceValue(keys...)
+}
+
+func stringsOf(value interface{}) []string {
+	values, _ := value.([]interface{})
+	return compiler.ConvertInterfaceArrayToStringArray(values)
+}
+
+// servers combines schemes, host and basePath into server URLs.
+func (c *swaggerConverter) servers() []interface{} {
+	host, _ := c.value("host").(string)
+	basePath, _ := c.value("basePath").(string)
+	if len(host) == 0 && len(basePath) == 0 {
+		return nil
+	}
+	if len(host) == 0 {
+		return []interface{}{yaml.MapSlice{{Key: "url", Value: basePath}}}
+	}
+
+	schemes := stringsOf(c.value("schemes"))
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+	servers := []interface{}{}
+	for _, scheme := range schemes {
+		servers = append(servers, yaml.MapSlice{{Key: "url", Value: scheme + "://" + host + basePath}})
+	}
+	return servers
+}
+
+func (c *swaggerConverter) components() yaml.MapSlice {
+	components := yaml.MapSlice{}
+
+	if names, values := entries(c.value("definitions")); len(names) > 0 {
+		schemas := yaml.MapSlice{}
+		for i, name := range names {
+			schemas = append(schemas, yaml.MapItem{Key: name, Value: convertSwaggerSchema(values[i])})
+		}
+		components = append(components, yaml.MapItem{Key: "schemas", Value: schemas})
+	}
+
+	if names, values := entries(c.value("parameters")); len(names) > 0 {
+		parameters := yaml.MapSlice{}
+		for i, name := range names {
+			// body and form parameters become part of a request body
+			if in := stringValue(values[i], "in"); in != "body" && in != "formData" {
+				parameters = append(parameters, yaml.MapItem{Key: name, Value: c.parameter(values[i], jsonPointer("parameters", name))})
+			}
+		}
+		if len(parameters) > 0 {
+			components = append(components, yaml.MapItem{Key: "parameters", Value: parameters})
+		}
+	}
+
+	if names, values := entries(c.value("responses")); len(names) > 0 {
+		responses := yaml.MapSlice{}
+		produces := stringsOf(c.value("produces"))
+		for i, name := range names {
+			responses = append(responses, yaml.MapItem{Key: name, Value: c.response(values[i], produces)})
+		}
+		components = append(components, yaml.MapItem{Key: "responses", Value: responses})
+	}
+
+	if names, values := entries(c.value("securityDefinitions")); len(names) > 0 {
+		schemes := yaml.MapSlice{}
+		for i, name := range names {
+			schemes = append(schemes, yaml.MapItem{Key: name, Value: c.securityScheme(values[i], jsonPointer("securityDefinitions", name))})
+		}
+		components = append(components, yaml.MapItem{Key: "securitySchemes", Value: schemes})
+	}
+
+	return components
+}
+
+var oauthFlows = map[string]string{
+	"implicit":    "implicit",
+	"password":    "password",
+	"application": "clientCredentials",
+	"accessCode":  "authorizationCode",
+}
+
+func (c *swaggerConverter) securityScheme(value interface{}, pointer string) yaml.MapSlice {
+	scheme := yaml.MapSlice{}
+	if description := stringValue(value, "description"); len(description) > 0 {
+		scheme = append(scheme, yaml.MapItem{Key: "description", Value: description})
+	}
+
+	switch stringValue(value, "type") {
+	case "basic":
+		return append(scheme, yaml.MapItem{Key: "type", Value: "http"}, yaml.MapItem{Key: "scheme", Value: "basic"})
+	case "apiKey":
+		return append(scheme,
+			yaml.MapItem{Key: "type", Value: "apiKey"},
+			yaml.MapItem{Key: "name", Value: stringValue(value, "name")},
+			yaml.MapItem{Key: "in", Value: stringValue(value, "in")},
+		)
+	case "oauth2":
+		flow := yaml.MapSlice{}
+		name := stringValue(value, "flow")
+		if url := stringValue(value, "authorizationUrl"); len(url) > 0 {
+			flow = append(flow, yaml.MapItem{Key: "authorizationUrl", Value: url})
+		}
+		if url := stringValue(value, "tokenUrl"); len(url) > 0 {
+			flow = append(flow, yaml.MapItem{Key: "tokenUrl", Value: url})
+		}
+		scopes := compiler.MapValueForKey(mapOf(value), "scopes")
+		if scopes == nil {
+			scopes = yaml.MapSlice{}
+		}
+		flow = append(flow, yaml.MapItem{Key: "scopes", Value: scopes})
+		return append(scheme,
+			yaml.MapItem{Key: "type", Value: "oauth2"},
+			yaml.MapItem{Key: "flows", Value: yaml.MapSlice{{Key: oauthFlows[name], Value: flow}}},
+		)
+	}
+
+	c.warnf(pointer, "unknown security scheme type %q", stringValue(value, "type"))
+	return scheme
+}
+
+var swaggerMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+func (c *swaggerConverter) paths() yaml.MapSlice {
+	paths := yaml.MapSlice{}
+	globalConsumes := stringsOf(c.value("consumes"))
+	globalProduces := stringsOf(c.value("produces"))
+
+	names, items := entries(c.value("paths"))
+	for i, path := range names {
+		pointer := jsonPointer("paths", path)
+		pathParameters, _ := compiler.MapValueForKey(mapOf(items[i]), "parameters").([]interface{})
+
+		item := yaml.MapSlice{}
+		for _, entry := range mapOf(items[i]) {
+			key := fmt.Sprint(entry.Key)
+			switch {
+			case contains(swaggerMethods, key):
+				item = append(item, yaml.MapItem{Key: key, Value: c.operation(entry.Value, pointer+jsonPointer(key), pathParameters, globalConsumes, globalProduces)})
+			case key == "$ref":
+				c.warnf(pointer+"/$ref", "path item references are not supported")
+			case strings.HasPrefix(key, "x-"):
+				item = append(item, entry)
+			}
+		}
+		paths = append(paths, yaml.MapItem{Key: path, Value: item})
+	}
+	return paths
+}
+
+// resolveParameter returns the global parameter a reference points to, or
+// the parameter itself.
+func (c *swaggerConverter) resolveParameter(value interface{}) interface{} {
+	ref := stringValue(value, "$ref")
+	if !strings.HasPrefix(ref, "#/parameters/") {
+		return value
+	}
+	return c.value(refKeys(ref)...)
+}
+
+func (c *swaggerConverter) operation(value interface{}, pointer string, pathParameters []interface{}, globalConsumes []string, globalProduces []string) yaml.MapSlice {
+	op := yaml.MapSlice{}
+	for _, item := range mapOf(value) {
+		switch key := fmt.Sprint(item.Key); key {
+		case "tags", "summary", "description", "externalDocs", "operationId", "deprecated", "security":
+			op = append(op, item)
+		default:
+			if strings.HasPrefix(key, "x-") {
+				op = append(op, item)
+			}
+		}
+	}
+
+	consumes := stringsOf(compiler.MapValueForKey(mapOf(value), "consumes"))
+	if len(consumes) == 0 {
+		consumes = globalConsumes
+	}
+	produces := stringsOf(compiler.MapValueForKey(mapOf(value), "produces"))
+	if len(produces) == 0 {
+		produces = globalProduces
+	}
+
+	// operation parameters override path level ones with the same name and
+	// location
+	operationParameters, _ := compiler.MapValueForKey(mapOf(value), "parameters").([]interface{})
+	type source struct {
+		value   interface{}
+		pointer string
+	}
+	var sources []source
+	overridden := map[string]bool{}
+	for i, p := range operationParameters {
+		p = c.resolveParameter(p)
+		overridden[stringValue(p, "in")+" "+stringValue(p, "name")] = true
+		sources = append(sources, source{p, fmt.Sprintf("%s/parameters/%d", pointer, i)})
+	}
+	for i, p := range pathParameters {
+		p = c.resolveParameter(p)
+		if !overridden[stringValue(p, "in")+" "+stringValue(p, "name")] {
+			sources = append(sources, source{p, fmt.Sprintf("%s/parameters/%d", parentPointer(pointer), i)})
+		}
+	}
+
+	parameters := []interface{}{}
+	var body interface{}
+	var form []interface{}
+	for _, s := range sources {
+		switch stringValue(s.value, "in") {
+		case "body":
+			body = s.value
+		case "formData":
+			form = append(form, s.value)
+		case "":
+			c.warnf(s.pointer, "the parameter could not be resolved")
+		default:
+			parameters = append(parameters, c.parameter(s.value, s.pointer))
+		}
+	}
+	if len(parameters) > 0 {
+		op = append(op, yaml.MapItem{Key: "parameters", Value: parameters})
+	}
+
+	if body != nil {
+		op = append(op, yaml.MapItem{Key: "requestBody", Value: requestBody(body, consumes)})
+	} else if len(form) > 0 {
+		op = append(op, yaml.MapItem{Key: "requestBody", Value: formBody(form, consumes)})
+	}
+
+	responses := yaml.MapSlice{}
+	codes, values := entries(compiler.MapValueForKey(mapOf(value), "responses"))
+	for i, code := range codes {
+		response := values[i]
+		if ref := stringValue(response, "$ref"); strings.HasPrefix(ref, "#/responses/") {
+			response = c.value(refKeys(ref)...)
+		}
+		responses = append(responses, yaml.MapItem{Key: code, Value: c.response(response, produces)})
+	}
+	op = append(op, yaml.MapItem{Key: "responses", Value: responses})
+
+	return op
+}
+
+// parameter converts a query, path, header or cookie parameter, moving the
+// fields that describe its value into a schema.
+func (c *swaggerConverter) parameter(value interface{}, pointer string) yaml.MapSlice {
+	p := yaml.MapSlice{}
+	for _, key := range []string{"name", "in", "description", "required", "allowEmptyValue"} {
+		if v := compiler.MapValueForKey(mapOf(value), key); v != nil {
+			p = append(p, yaml.MapItem{Key: key, Value: v})
+		}
+	}
+
+	// explode and the delimited styles only apply to arrays
+	if stringValue(value, "type") == "array" {
+		p = append(p, c.collectionStyle(value, pointer)...)
+	}
+
+	return append(p, yaml.MapItem{Key: "schema", Value: parameterSchema(value)})
+}
+
+// collectionStyle converts the collectionFormat of an array parameter into
+// its style and explode.
+func (c *swaggerConverter) collectionStyle(value interface{}, pointer string) yaml.MapSlice {
+	in := stringValue(value, "in")
+	switch format := stringValue(value, "collectionFormat"); format {
+	case "", "csv":
+		if in == "query" || in == "cookie" {
+			return yaml.MapSlice{{Key: "explode", Value: false}}
+		}
+		return nil
+	case "multi":
+		if in != "query" {
+			c.warnf(pointer+"/collectionFormat", "collectionFormat multi is only allowed for query parameters")
+		}
+		return yaml.MapSlice{{Key: "style", Value: "form"}, {Key: "explode", Value: true}}
+	case "ssv":
+		return yaml.MapSlice{{Key: "style", Value: "spaceDelimited"}, {Key: "explode", Value: false}}
+	case "pipes":
+		return yaml.MapSlice{{Key: "style", Value: "pipeDelimited"}, {Key: "explode", Value: false}}
+	default:
+		c.warnf(pointer+"/collectionFormat", "collectionFormat %s is not supported; csv is used instead", format)
+		if in == "query" {
+			return yaml.MapSlice{{Key: "explode", Value: false}}
+		}
+		return nil
+	}
+}
+
+// parameterSchema builds a schema from the value fields of a non-body
+// parameter, or of its items.
+func parameterSchema(value interface{}) yaml.MapSlice {
+	schema := yaml.MapSlice{}
+	for _, key := range parameterFields {
+		if v := compiler.MapValueForKey(mapOf(value), key); v != nil {
+			if key == "type" && v == "file" {
+				schema = append(schema, yaml.MapItem{Key: "type", Value: "string"}, yaml.MapItem{Key: "format", Value: "binary"})
+				continue
+			}
+			schema = append(schema, yaml.MapItem{Key: key, Value: v})
+		}
+	}
+	if items := compiler.MapValueForKey(mapOf(value), "items"); items != nil {
+		schema = append(schema, yaml.MapItem{Key: "items", Value: parameterSchema(items)})
+	}
+	return schema
+}
+
+func requestBody(body interface{}, consumes []string) yaml.MapSlice {
+	if len(consumes) == 0 {
+		consumes = []string{"application/json"}
+	}
+
+	content := yaml.MapSlice{}
+	schema := convertSwaggerSchema(compiler.MapValueForKey(mapOf(body), "schema"))
+	for _, mediaType := range consumes {
+		if !IsFormMediaType(mediaType) {
+			content = append(content, yaml.MapItem{Key: mediaType, Value: yaml.MapSlice{{Key: "schema", Value: schema}}})
+		}
+	}
+
+	result := yaml.MapSlice{}
+	if description := stringValue(body, "description"); len(description) > 0 {
+		result = append(result, yaml.MapItem{Key: "description", Value: description})
+	}
+	if required, _ := compiler.MapValueForKey(mapOf(body), "required").(bool); required {
+		result = append(result, yaml.MapItem{Key: "required", Value: true})
+	}
+	return append(result, yaml.MapItem{Key: "content", Value: content})
+}
+
+// formBody combines formData parameters into an object schema, for each of
+// the form media types the operation consumes.
+func formBody(form []interface{}, consumes []string) yaml.MapSlice {
+	properties := yaml.MapSlice{}
+	var required []interface{}
+	hasFile := false
+	for _, p := range form {
+		name := stringValue(p, "name")
+		schema := parameterSchema(p)
+		if description := stringValue(p, "description"); len(description) > 0 {
+			schema = append(schema, yaml.MapItem{Key: "description", Value: description})
+		}
+		properties = append(properties, yaml.MapItem{Key: name, Value: schema})
+		if r, _ := compiler.MapValueForKey(mapOf(p), "required").(bool); r {
+			required = append(required, name)
+		}
+		hasFile = hasFile || stringValue(p, "type") == "file"
+	}
+
+	schema := yaml.MapSlice{{Key: "type", Value: "object"}}
+	if len(required) > 0 {
+		schema = append(schema, yaml.MapItem{Key: "required", Value: required})
+	}
+	schema = append(schema, yaml.MapItem{Key: "properties", Value: properties})
+
+	var mediaTypes []string
+	for _, mediaType := range consumes {
+		if IsFormMediaType(mediaType) {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	if len(mediaTypes) == 0 {
+		mediaTypes = []string{"application/x-www-form-urlencoded"}
+		if hasFile {
+			mediaTypes = []string{"multipart/form-data"}
+		}
+	}
+
+	content := yaml.MapSlice{}
+	for _, mediaType := range mediaTypes {
+		content = append(content, yaml.MapItem{Key: mediaType, Value: yaml.MapSlice{{Key: "schema", Value: schema}}})
+	}
+	return yaml.MapSlice{{Key: "content", Value: content}}
+}
+
+// response moves a response's schema and examples into content for each
+// media type the operation produces.
+func (c *swaggerConverter) response(value interface{}, produces []string) yaml.MapSlice {
+	response := yaml.MapSlice{{Key: "description", Value: stringValue(value, "description")}}
+
+	if names, headers := entries(compiler.MapValueForKey(mapOf(value), "headers")); len(names) > 0 {
+		converted := yaml.MapSlice{}
+		for i, name := range names {
+			header := yaml.MapSlice{}
+			if description := stringValue(headers[i], "description"); len(description) > 0 {
+				header = append(header, yaml.MapItem{Key: "description", Value: description})
+			}
+			header = append(header, yaml.MapItem{Key: "schema", Value: parameterSchema(headers[i])})
+			converted = append(converted, yaml.MapItem{Key: name, Value: header})
+		}
+		response = append(response, yaml.MapItem{Key: "headers", Value: converted})
+	}
+
+	schema := compiler.MapValueForKey(mapOf(value), "schema")
+	if schema == nil {
+		return response
+	}
+	if len(produces) == 0 {
+		produces = []string{"application/json"}
+	}
+
+	examples := mapOf(compiler.MapValueForKey(mapOf(value), "examples"))
+	content := yaml.MapSlice{}
+	for _, mediaType := range produces {
+		mediaTypeObject := yaml.MapSlice{{Key: "schema", Value: convertSwaggerSchema(schema)}}
+		if example := compiler.MapValueForKey(examples, mediaType); example != nil {
+			mediaTypeObject = append(mediaTypeObject, yaml.MapItem{Key: "example", Value: example})
+		}
+		content = append(content, yaml.MapItem{Key: mediaType, Value: mediaTypeObject})
+	}
+	return append(response, yaml.MapItem{Key: "content", Value: content})
+}
+
+// convertSwaggerSchema rewrites the parts of a Swagger schema that differ in
+// OpenAPI 3.0.
+func convertSwaggerSchema(value interface{}) interface{} {
+	m, ok := compiler.UnpackMap(value)
+	if !ok {
+		return value
+	}
+
+	schema := yaml.MapSlice{}
+	for _, item := range m {
+		switch key := fmt.Sprint(item.Key); key {
+		case "type":
+			if item.Value == "file" {
+				schema = append(schema, yaml.MapItem{Key: "type", Value: "string"}, yaml.MapItem{Key: "format", Value: "binary"})
+				continue
+			}
+			schema = append(schema, item)
+		case "x-nullable":
+			schema = append(schema, yaml.MapItem{Key: "nullable", Value: item.Value})
+		case "discriminator":
+			if name, ok := item.Value.(string); ok {
+				item.Value = yaml.MapSlice{{Key: "propertyName", Value: name}}
+			}
+			schema = append(schema, item)
+		case "properties":
+			properties := yaml.MapSlice{}
+			for _, p := range mapOf(item.Value) {
+				properties = append(properties, yaml.MapItem{Key: p.Key, Value: convertSwaggerSchema(p.Value)})
+			}
+			schema = append(schema, yaml.MapItem{Key: key, Value: properties})
+		case "items", "additionalProperties":
+			schema = append(schema, yaml.MapItem{Key: key, Value: convertSwaggerSchema(item.Value)})
+		case "allOf":
+			subschemas, _ := item.Value.([]interface{})
+			converted := make([]interface{}, len(subschemas))
+			for i, s := range subschemas {
+				converted[i] = convertSwaggerSchema(s)
+			}
+			schema = append(schema, yaml.MapItem{Key: key, Value: converted})
+		default:
+			schema = append(schema, item)
+		}
+	}
+	return schema
+}
+
+var swaggerRefPrefixes = map[string]string{
+	"#/definitions/": "#/components/schemas/",
+	"#/parameters/":  "#/components/parameters/",
+	"#/responses/":   "#/components/responses/",
+}
+
+// rewriteSwaggerRefs points references at the components they were moved
+// to.
+func rewriteSwaggerRefs(value interface{}) interface{} {
+	switch value := value.(type) {
+	case yaml.MapSlice:
+		for i, item := range value {
+			if ref, ok := item.Value.(string); ok && fmt.Sprint(item.Key) == "$ref" {
+				for old, replacement := range swaggerRefPrefixes {
+					if strings.HasPrefix(ref, old) {
+						value[i].Value = replacement + strings.TrimPrefix(ref, old)
+					}
+				}
+			} else {
+				value[i].Value = rewriteSwaggerRefs(item.Value)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = rewriteSwaggerRefs(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,214 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	openapi_v3 "github.com/googleapis/gnostic/OpenAPIv3"
+	"github.com/googleapis/gnostic/compiler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertSwagger2(t *testing.T) {
+	cases := []struct {
+		name string
+		// everything after info of the Swagger document
+		swagger string
+		// everything after info of the OpenAPI document
+		expected    string
+		diagnostics []string
+	}{
+		{
+			name: "servers",
+			swagger: `
+host: api.example.com
+basePath: /v1
+schemes: [http, https]
+paths: {}
+`,
+			expected: `
+servers: [{url: "http://api.example.com/v1"}, {url: "https://api.example.com/v1"}]
+paths: {}
+`,
+			diagnostics: []string{},
+		},
+		{
+			name: "bodies",
+			swagger: `
+consumes: [application/json]
+produces: [application/json, application/xml]
+paths:
+	/items:
+		post:
+			operationId: createItem
+			parameters:
+				- {name: item, in: body, required: true, schema: {$ref: "#/definitions/Item"}}
+			responses:
+				"201":
+					description: created
+					headers: {Location: {type: string, description: where}}
+					schema: {$ref: "#/definitions/Item"}
+					examples: {application/json: {name: a}}
+				default: {$ref: "#/responses/Error"}
+definitions:
+	Item:
+		type: object
+		discriminator: kind
+		properties:
+			kind: {type: string}
+			name: {type: string, x-nullable: true}
+responses:
+	Error: {description: error}
+`,
+			expected: `
+paths:
+	/items:
+		post:
+			operationId: createItem
+			requestBody:
+				required: true
+				content:
+					application/json: {schema: {$ref: "#/components/schemas/Item"}}
+			responses:
+				"201":
+					description: created
+					headers: {Location: {description: where, schema: {type: string}}}
+					content:
+						application/json: {schema: {$ref: "#/components/schemas/Item"}, example: {name: a}}
+						application/xml: {schema: {$ref: "#/components/schemas/Item"}}
+				default: {description: error}
+components:
+	schemas:
+		Item:
+			type: object
+			discriminator: {propertyName: kind}
+			properties:
+				kind: {type: string}
+				name: {type: string, nullable: true}
+	responses:
+		Error: {description: error}
+`,
+			diagnostics: []string{},
+		},
+		{
+			name: "forms",
+			swagger: `
+paths:
+	/files:
+		post:
+			operationId: upload
+			parameters:
+				- {name: file, in: formData, type: file, required: true}
+				- {name: note, in: formData, type: string, description: about the file}
+			responses:
+				"204": {description: uploaded}
+`,
+			expected: `
+paths:
+	/files:
+		post:
+			operationId: upload
+			requestBody:
+				content:
+					multipart/form-data:
+						schema:
+							type: object
+							required: [file]
+							properties:
+								file: {type: string, format: binary}
+								note: {type: string, description: about the file}
+			responses:
+				"204": {description: uploaded}
+`,
+			diagnostics: []string{},
+		},
+		{
+			name: "parameters",
+			swagger: `
+paths:
+	/items/{id}:
+		parameters:
+			- {name: id, in: path, required: true, type: integer}
+			- {$ref: "#/parameters/Limit"}
+		get:
+			operationId: getItem
+			parameters:
+				- {name: limit, in: query, type: integer, maximum: 10}
+				- {name: tags, in: query, type: array, items: {type: string}, collectionFormat: multi}
+				- {name: ids, in: query, type: array, items: {type: integer}, collectionFormat: pipes}
+				- {name: X-Fields, in: header, type: array, items: {type: string}, collectionFormat: tsv}
+			responses:
+				"200": {description: ok}
+parameters:
+	Limit: {name: limit, in: query, type: integer}
+`,
+			expected: `
+paths:
+	/items/{id}:
+		get:
+			operationId: getItem
+			parameters:
+				- {name: limit, in: query, schema: {type: integer, maximum: 10}}
+				- {name: tags, in: query, style: form, explode: true, schema: {type: array, items: {type: string}}}
+				- {name: ids, in: query, style: pipeDelimited, explode: false, schema: {type: array, items: {type: integer}}}
+				- {name: X-Fields, in: header, schema: {type: array, items: {type: string}}}
+				- {name: id, in: path, required: true, schema: {type: integer}}
+			responses:
+				"200": {description: ok}
+components:
+	parameters:
+		Limit: {name: limit, in: query, schema: {type: integer}}
+`,
+			diagnostics: []string{"warning /paths/~1items~1{id}/get/parameters/3/collectionFormat"},
+		},
+		{
+			name: "security",
+			swagger: `
+security: [{token: []}]
+paths: {}
+securityDefinitions:
+	basic: {type: basic}
+	token: {type: apiKey, name: X-Token, in: header}
+	oauth: {type: oauth2, flow: accessCode, authorizationUrl: "https://a.example.com", tokenUrl: "https://t.example.com", scopes: {read: read items}}
+	other: {type: mutual}
+`,
+			expected: `
+security: [{token: []}]
+paths: {}
+components:
+	securitySchemes:
+		basic: {type: http, scheme: basic}
+		token: {type: apiKey, name: X-Token, in: header}
+		oauth:
+			type: oauth2
+			flows:
+				authorizationCode: {authorizationUrl: "https://a.example.com", tokenUrl: "https://t.example.com", scopes: {read: read items}}
+		other: {}
+`,
+			diagnostics: []string{"warning /securityDefinitions/other"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			info, _ := readSpec(t, fmt.Sprintf(`
+swagger: "2.0"
+info: {title: t, version: "1"}
+%s`, c.swagger))
+			require.True(t, IsSwagger2(info))
+
+			doc, diags := ConvertSwagger2(info)
+			assertYAML(t, fmt.Sprintf(`
+openapi: 3.0.3
+info: {title: t, version: "1"}
+%s`, c.expected), doc)
+			assert.Equal(t, c.diagnostics, diagnosticsOf(diags))
+
+			if len(c.diagnostics) == 0 {
+				_, err := openapi_v3.NewDocument(doc, compiler.NewContext("$root", nil))
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,490 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/googleapis/gnostic/compiler"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/mllrjb/hackathon-go-openapi-v3/utils"
+)
+
+// Severity of a Diagnostic. Errors stop the generator or make it write
+// broken code; warnings are parts of the spec that it ignores.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found in a spec.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	// Code identifies the kind of problem, e.g. unresolved-ref.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Pointer is the JSON pointer of the offending value in the document.
+	Pointer string `json:"pointer"`
+	// File, Line and Column are filled in by Locate, if it can find the
+	// value.
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+func (d Diagnostic) String() string {
+	location := d.File
+	if d.Line > 0 {
+		location = fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column)
+	}
+	if len(location) > 0 {
+		location += ": "
+	}
+	return fmt.Sprintf("%s%s: %s (%s) [%s]", location, d.Severity, d.Message, pointerOrRoot(d.Pointer), d.Code)
+}
+
+func pointerOrRoot(pointer string) string {
+	if len(pointer) == 0 {
+		return "#"
+	}
+	return "#" + pointer
+}
+
+// DocumentDiagnostics turns the error from openapi_v3.NewDocument into a
+// diagnostic per problem, each pointing at the value it is about. gnostic
+// tries each alternative of a oneOf, naming them in its context, so names
+// that aren't keys in the source are left out of the pointer.
+func (o *Walker) DocumentDiagnostics(err error) []Diagnostic {
+	diags := []Diagnostic{}
+	seen := map[Diagnostic]bool{}
+	for _, diag := range o.documentDiagnostics(err) {
+		if !seen[diag] {
+			seen[diag] = true
+			diags = append(diags, diag)
+		}
+	}
+	return diags
+}
+
+func (o *Walker) documentDiagnostics(err error) []Diagnostic {
+	var group *compiler.ErrorGroup
+	if errors.As(err, &group) {
+		diags := []Diagnostic{}
+		for _, e := range group.Errors {
+			diags = append(diags, o.documentDiagnostics(e)...)
+		}
+		return diags
+	}
+
+	diag := Diagnostic{Severity: SeverityError, Code: "invalid-document", Message: err.Error()}
+	var compilerErr *compiler.Error
+	if errors.As(err, &compilerErr) {
+		diag.Message = compilerErr.Message
+		var names []string
+		for c := compilerErr.Context; c != nil && c.Parent != nil; c = c.Parent {
+			names = append([]string{c.Name}, names...)
+		}
+
+		var keys []string
+		for _, name := range names {
+			if o.sourceValue(append(keys, name)...) != nil {
+				keys = append(keys, name)
+			}
+		}
+		diag.Pointer = jsonPointer(keys...)
+	}
+	return []Diagnostic{diag}
+}
+
+// Validate checks the raw document (see SetSource) for everything the
+// generator can't handle, collecting every problem rather than stopping at
+// the first. It doesn't need Traverse to have been called, and the document
+// needn't be one that Traverse accepts.
+func (o *Walker) Validate() []Diagnostic {
+	v := &validator{walker: o}
+
+	v.checkRefs(o.source, "")
+	v.checkPaths()
+	v.checkComponents()
+
+	sort.SliceStable(v.diags, func(i, j int) bool {
+		return v.diags[i].Pointer < v.diags[j].Pointer
+	})
+	return v.diags
+}
+
+type validator struct {
+	walker *Walker
+	diags  []Diagnostic
+}
+
+func (v *validator) report(severity Severity, code string, pointer string, format string, args ...interface{}) {
+	v.diags = append(v.diags, Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Pointer:  pointer,
+	})
+}
+
+func (v *validator) errorf(code string, pointer string, format string, args ...interface{}) {
+	v.report(SeverityError, code, pointer, format, args...)
+}
+
+func (v *validator) warnf(code string, pointer string, format string, args ...interface{}) {
+	v.report(SeverityWarning, code, pointer, format, args...)
+}
+
+// jsonPointer joins keys into a JSON pointer, escaping them.
+func jsonPointer(keys ...string) string {
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString("/")
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(key))
+	}
+	return b.String()
+}
+
+// entries returns the keys of a mapping as strings, with their values, in
+// document order.
+func entries(value interface{}) ([]string, []interface{}) {
+	m, ok := compiler.UnpackMap(value)
+	if !ok {
+		return nil, nil
+	}
+	keys := make([]string, len(m))
+	values := make([]interface{}, len(m))
+	for i, item := range m {
+		keys[i] = fmt.Sprint(item.Key)
+		values[i] = item.Value
+	}
+	return keys, values
+}
+
+func stringValue(value interface{}, key string) string {
+	m, _ := compiler.UnpackMap(value)
+	s, _ := compiler.MapValueForKey(m, key).(string)
+	return s
+}
+
+// checkRefs reports every $ref that doesn't resolve.
+func (v *validator) checkRefs(value interface{}, pointer string) {
+	switch value := value.(type) {
+	case yaml.MapSlice:
+		for _, item := range value {
+			key := fmt.Sprint(item.Key)
+			child := pointer + jsonPointer(key)
+			ref, isString := item.Value.(string)
+			if key != "$ref" || !isString {
+				v.checkRefs(item.Value, child)
+				continue
+			}
+
+			if !strings.HasPrefix(ref, "#/") {
+				v.errorf("external-ref", child, "$ref %q points outside the document, which is not supported", ref)
+			} else if v.walker.sourceValue(refKeys(ref)...) == nil {
+				v.errorf("unresolved-ref", child, "$ref %q does not resolve", ref)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			v.checkRefs(item, pointer+jsonPointer(strconv.Itoa(i)))
+		}
+	}
+}
+
+// resolve follows a reference object to the value it points at, returning
+// value itself if it isn't one, or nil if it doesn't resolve.
+func (v *validator) resolve(value interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		ref := stringValue(value, "$ref")
+		if len(ref) == 0 || !strings.HasPrefix(ref, "#/") {
+			return value
+		}
+		value = v.walker.sourceValue(refKeys(ref)...)
+	}
+	return nil
+}
+
+var operationMethods = map[string]bool{"get": true, "put": true, "post": true, "delete": true, "patch": true}
+
+var pathTemplate = regexp.MustCompile(`\{([^}]+)\}`)
+
+func (v *validator) checkPaths() {
+	operationIDs := map[string]string{}
+	names := map[string]string{}
+
+	paths, items := entries(v.walker.sourceValue("paths"))
+	for i, path := range paths {
+		pathPointer := jsonPointer("paths", path)
+		keys, values := entries(items[i])
+
+		var pathParameters []interface{}
+		for j, key := range keys {
+			switch {
+			case key == "parameters":
+				pathParameters, _ = values[j].([]interface{})
+				if len(pathParameters) > 0 {
+					v.warnf("unsupported", pathPointer+"/parameters", "path level parameters are ignored; declare them on each operation")
+				}
+			case key == "$ref":
+				v.warnf("unsupported", pathPointer+"/$ref", "path item references are not supported; the path is ignored")
+			case key == "head" || key == "options" || key == "trace":
+				v.warnf("unsupported", pathPointer+jsonPointer(key), "%s operations are not generated", strings.ToUpper(key))
+			}
+		}
+
+		for j, method := range keys {
+			if !operationMethods[method] {
+				continue
+			}
+			opPointer := pathPointer + jsonPointer(method)
+			op := values[j]
+
+			id := stringValue(op, "operationId")
+			if len(id) == 0 {
+				v.errorf("missing-operation-id", opPointer, "%s %s has no operationId, which the generated names are based on", strings.ToUpper(method), path)
+			} else if previous, ok := operationIDs[id]; ok {
+				v.errorf("duplicate-operation-id", opPointer+"/operationId", "operationId %s is also used by %s", id, previous)
+			} else if previous, ok := names[utils.ToPascalCase(id)]; ok {
+				v.errorf("name-collision", opPointer+"/operationId", "operationId %s and %s both generate the name %s", id, previous, utils.ToPascalCase(id))
+			} else {
+				operationIDs[id] = strings.ToUpper(method) + " " + path
+				names[utils.ToPascalCase(id)] = id
+			}
+
+			v.checkOperation(path, opPointer, op, pathParameters)
+		}
+	}
+}
+
+func (v *validator) checkOperation(path string, pointer string, op interface{}, pathParameters []interface{}) {
+	declared := map[string]bool{}
+	parameters, _ := compiler.MapValueForKey(mapOf(op), "parameters").([]interface{})
+	for i, p := range parameters {
+		paramPointer := pointer + jsonPointer("parameters", strconv.Itoa(i))
+		if len(stringValue(p, "$ref")) > 0 {
+			v.warnf("unsupported", paramPointer, "parameter references are not supported; the parameter is ignored")
+			continue
+		}
+
+		name, in := stringValue(p, "name"), stringValue(p, "in")
+		if in == "path" {
+			declared[name] = true
+			if required, _ := compiler.MapValueForKey(mapOf(p), "required").(bool); !required {
+				v.errorf("path-parameter-not-required", paramPointer, "path parameter %s must be required", name)
+			}
+			if !strings.Contains(path, "{"+name+"}") {
+				v.errorf("undeclared-path-parameter", paramPointer, "path parameter %s is not in the path %s", name, path)
+			}
+		}
+		v.checkParameter(paramPointer, p)
+	}
+
+	for _, match := range pathTemplate.FindAllStringSubmatch(path, -1) {
+		name := match[1]
+		if declared[name] {
+			continue
+		}
+		message := fmt.Sprintf("path parameter %s is not declared", name)
+		for _, p := range pathParameters {
+			if stringValue(p, "name") == name && stringValue(p, "in") == "path" {
+				message = fmt.Sprintf("path parameter %s is only declared at the path level, which is ignored", name)
+			}
+		}
+		v.errorf("missing-path-parameter", pointer, "%s", message)
+	}
+
+	if body := compiler.MapValueForKey(mapOf(op), "requestBody"); body != nil {
+		bodyPointer := pointer + "/requestBody"
+		if len(stringValue(body, "$ref")) > 0 {
+			v.errorf("unsupported", bodyPointer, "request body references are not supported")
+		} else {
+			mediaTypes, values := entries(compiler.MapValueForKey(mapOf(body), "content"))
+			for i, mediaType := range mediaTypes {
+				mediaPointer := bodyPointer + jsonPointer("content", mediaType)
+				schema := compiler.MapValueForKey(mapOf(values[i]), "schema")
+				if schema == nil {
+					v.errorf("missing-schema", mediaPointer, "request body %s has no schema", mediaType)
+					continue
+				}
+				if IsFormMediaType(mediaType) && schemaType(v.resolve(schema)) != "object" {
+					v.errorf("form-not-object", mediaPointer+"/schema", "%s request body must be an object", mediaType)
+				}
+				v.checkSchema(mediaPointer+"/schema", schema)
+			}
+		}
+	}
+
+	codes, responses := entries(compiler.MapValueForKey(mapOf(op), "responses"))
+	for i, code := range codes {
+		responsePointer := pointer + jsonPointer("responses", code)
+		if len(stringValue(responses[i], "$ref")) > 0 {
+			v.warnf("unsupported", responsePointer, "response references are not supported; the response is ignored")
+			continue
+		}
+		if compiler.MapValueForKey(mapOf(responses[i]), "links") != nil {
+			v.warnf("unsupported", responsePointer+"/links", "links are ignored")
+		}
+		mediaTypes, values := entries(compiler.MapValueForKey(mapOf(responses[i]), "content"))
+		for j, mediaType := range mediaTypes {
+			if schema := compiler.MapValueForKey(mapOf(values[j]), "schema"); schema != nil {
+				v.checkSchema(responsePointer+jsonPointer("content", mediaType, "schema"), schema)
+			}
+		}
+		headers, headerValues := entries(compiler.MapValueForKey(mapOf(responses[i]), "headers"))
+		for j, header := range headers {
+			if schema := compiler.MapValueForKey(mapOf(headerValues[j]), "schema"); schema != nil {
+				v.checkSchema(responsePointer+jsonPointer("headers", header, "schema"), schema)
+			}
+		}
+	}
+
+	if compiler.MapValueForKey(mapOf(op), "callbacks") != nil {
+		v.warnf("unsupported", pointer+"/callbacks", "callbacks are ignored")
+	}
+
+	v.checkSecurity(pointer+"/security", compiler.MapValueForKey(mapOf(op), "security"))
+}
+
+func mapOf(value interface{}) yaml.MapSlice {
+	m, _ := compiler.UnpackMap(value)
+	return m
+}
+
+// schemaType returns the type of a schema, or "" if it has none.
+func schemaType(schema interface{}) string {
+	return stringValue(schema, "type")
+}
+
+func (v *validator) checkParameter(pointer string, p interface{}) {
+	schema := compiler.MapValueForKey(mapOf(p), "schema")
+	if schema == nil {
+		v.errorf("unsupported", pointer, "parameters without a schema, e.g. with content, are not supported")
+		return
+	}
+	v.checkSchema(pointer+"/schema", schema)
+
+	in := stringValue(p, "in")
+	style := stringValue(p, "style")
+	if len(style) == 0 {
+		style = defaultParameterStyle(in)
+	}
+
+	allowed, ok := parameterStyles[style]
+	if !ok {
+		v.errorf("parameter-style", pointer+"/style", "unknown style %q", style)
+		return
+	}
+	found := false
+	for _, a := range allowed {
+		found = found || a == in
+	}
+	if !found {
+		v.errorf("parameter-style", pointer+"/style", "style %s can't be used in %s", style, in)
+	}
+
+	typ := schemaType(v.resolve(schema))
+	switch {
+	case style == "deepObject" && typ != "object":
+		v.errorf("parameter-style", pointer, "style deepObject requires an object parameter")
+	case (style == "spaceDelimited" || style == "pipeDelimited") && typ != "object" && typ != "array":
+		v.errorf("parameter-style", pointer, "style %s requires an array or object parameter", style)
+	case typ == "object" && (in == "header" || in == "path") && style != "simple" && style != "label" && style != "matrix":
+		v.errorf("parameter-style", pointer, "object parameters in %s can't use style %s", in, style)
+	}
+}
+
+func (v *validator) checkSecurity(pointer string, security interface{}) {
+	requirements, _ := security.([]interface{})
+	for i, requirement := range requirements {
+		schemes, _ := entries(requirement)
+		for _, scheme := range schemes {
+			if v.walker.sourceValue("components", "securitySchemes", scheme) == nil {
+				v.errorf("unknown-security-scheme", pointer+jsonPointer(strconv.Itoa(i), scheme), "security scheme %s is not declared", scheme)
+			}
+		}
+	}
+}
+
+func (v *validator) checkComponents() {
+	v.checkSecurity("/security", v.walker.sourceValue("security"))
+
+	names := map[string]string{}
+	schemas, values := entries(v.walker.sourceValue("components", "schemas"))
+	for i, name := range schemas {
+		pointer := jsonPointer("components", "schemas", name)
+		goName := utils.ToPascalCase(name)
+		if previous, ok := names[goName]; ok {
+			v.errorf("name-collision", pointer, "schemas %s and %s both generate the type %s", name, previous, goName)
+		} else {
+			names[goName] = name
+		}
+		v.checkSchema(pointer, values[i])
+	}
+
+	headers, headerValues := entries(v.walker.sourceValue("components", "headers"))
+	for i, name := range headers {
+		if schema := compiler.MapValueForKey(mapOf(headerValues[i]), "schema"); schema != nil {
+			v.checkSchema(jsonPointer("components", "headers", name, "schema"), schema)
+		}
+	}
+}
+
+// checkSchema reports the parts of a schema, and of its subschemas, that the
+// generator ignores or can't turn into Go. References are checked where
+// they point to.
+func (v *validator) checkSchema(pointer string, schema interface{}) {
+	m := mapOf(schema)
+	if m == nil || len(stringValue(schema, "$ref")) > 0 {
+		return
+	}
+
+	typ := schemaType(schema)
+	if compiler.MapValueForKey(m, "not") != nil {
+		v.warnf("unsupported", pointer+"/not", "not is ignored")
+	}
+	if additional := compiler.MapValueForKey(m, "additionalProperties"); additional != nil && additional != false {
+		v.warnf("unsupported", pointer+"/additionalProperties", "additionalProperties is ignored; only the declared properties are generated")
+	}
+	if compiler.MapValueForKey(m, "allOf") != nil && typ != "object" {
+		v.warnf("unsupported", pointer+"/allOf", "allOf is only merged into schemas with type: object; it is ignored here")
+	}
+
+	properties, values := entries(compiler.MapValueForKey(m, "properties"))
+	if len(properties) > 0 && typ != "object" {
+		v.errorf("missing-type", pointer, "schema has properties but not type: object")
+	}
+	fields := map[string]string{}
+	for i, property := range properties {
+		propertyPointer := pointer + jsonPointer("properties", property)
+		field := utils.ToPascalCase(property)
+		if previous, ok := fields[field]; ok {
+			v.errorf("name-collision", propertyPointer, "properties %s and %s both generate the field %s", property, previous, field)
+		} else {
+			fields[field] = property
+		}
+		v.checkSchema(propertyPointer, values[i])
+	}
+
+	if typ == "array" {
+		items := compiler.MapValueForKey(m, "items")
+		if items == nil {
+			v.errorf("missing-items", pointer, "array schema has no items")
+		}
+		v.checkSchema(pointer+"/items", items)
+	}
+
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		subschemas, _ := compiler.MapValueForKey(m, key).([]interface{})
+		for i, subschema := range subschemas {
+			v.checkSchema(pointer+jsonPointer(key, strconv.Itoa(i)), subschema)
+		}
+	}
+}
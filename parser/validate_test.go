@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// readSpec unmarshals a spec written inline in a test, with tabs for
+// indentation so it can be lined up with the code.
+func readSpec(t *testing.T, spec string) (yaml.MapSlice, []byte) {
+	data := []byte(strings.ReplaceAll(spec, "\t", "  "))
+	var info yaml.MapSlice
+	require.NoError(t, yaml.Unmarshal(data, &info))
+	return info, data
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		spec string
+		// line:col severity code of each diagnostic
+		diagnostics []string
+	}{
+		{
+			name: "valid",
+			spec: `
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+	/items/{id}:
+		get:
+			operationId: getItem
+			parameters:
+				- {name: id, in: path, required: true, schema: {type: string}}
+			responses:
+				"200": {description: ok}
+`,
+			diagnostics: []string{},
+		},
+		{
+			name: "operations",
+			spec: `
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+	/items:
+		get:
+			responses:
+				"200": {description: ok}
+		post:
+			operationId: get-items
+			responses:
+				"200": {description: ok}
+		put:
+			operationId: getItems
+			responses:
+				"200": {description: ok}
+`,
+			diagnostics: []string{
+				"6:5 error missing-operation-id",
+				"14:7 error name-collision",
+			},
+		},
+		{
+			name: "parameters",
+			spec: `
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+	/items/{id}:
+		get:
+			operationId: getItem
+			parameters:
+				- {name: id, in: path, schema: {type: string}}
+				- {name: tags, in: query, style: deepObject, schema: {type: array, items: {type: string}}}
+			responses:
+				"200": {description: ok}
+`,
+			diagnostics: []string{
+				"9:11 error path-parameter-not-required",
+				"10:11 error parameter-style",
+			},
+		},
+		{
+			name: "refs and schemas",
+			spec: `
+openapi: 3.0.0
+info: {title: t, version: "1"}
+paths:
+	/items:
+		get:
+			operationId: getItems
+			security:
+				- token: []
+			responses:
+				"200":
+					description: ok
+					content:
+						application/json:
+							schema: {$ref: "#/components/schemas/Missing"}
+components:
+	schemas:
+		List:
+			type: array
+		Open:
+			type: object
+			additionalProperties: true
+`,
+			diagnostics: []string{
+				"18:5 error missing-items",
+				"22:7 warning unsupported",
+				"15:24 error unresolved-ref",
+				"9:11 error unknown-security-scheme",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			info, data := readSpec(t, c.spec)
+			w := NewWalker(nil)
+			w.SetSource(info)
+			diags := w.Validate()
+			require.NoError(t, Locate(diags, "spec.yaml", data))
+
+			actual := []string{}
+			for _, d := range diags {
+				assert.Equal(t, "spec.yaml", d.File)
+				actual = append(actual, fmt.Sprintf("%d:%d %s %s", d.Line, d.Column, d.Severity, d.Code))
+			}
+			assert.Equal(t, c.diagnostics, actual)
+		})
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	d := Diagnostic{
+		Severity: SeverityError,
+		Code:     "missing-items",
+		Message:  "array schema has no items",
+		Pointer:  "/components/schemas/List",
+		File:     "spec.yaml",
+		Line:     3,
+		Column:   5,
+	}
+	assert.Equal(t, "spec.yaml:3:5: error: array schema has no items (#/components/schemas/List) [missing-items]", d.String())
+
+	d.Line, d.Pointer = 0, ""
+	assert.Equal(t, "spec.yaml: error: array schema has no items (#) [missing-items]", d.String())
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/googleapis/gnostic/OpenAPIv3"
+	yaml "gopkg.in/yaml.v2"
 
 	"github.com/mllrjb/hackathon-go-openapi-v3/utils"
 )
@@ -19,16 +20,19 @@ import (
 // 1.3 walk allOf as schema
 
 type Walker struct {
-	document   *openapi_v3.Document
-	models     []SchemaModel
-	operations []*Operation
+	document        *openapi_v3.Document
+	source          yaml.MapSlice
+	models          []SchemaModel
+	operations      []*Operation
+	securitySchemes []*SecurityScheme
 }
 
 func NewWalker(document *openapi_v3.Document) Walker {
 	return Walker{
-		document:   document,
-		models:     []SchemaModel{},
-		operations: []*Operation{},
+		document:        document,
+		models:          []SchemaModel{},
+		operations:      []*Operation{},
+		securitySchemes: []*SecurityScheme{},
 	}
 }
 
@@ -60,8 +64,21 @@ func (o *Walker) GetOperations() []*Operation {
 	return o.operations
 }
 
+func (o *Walker) GetSecuritySchemes() []*SecurityScheme {
+	return o.securitySchemes
+}
+
+func (o *Walker) FindSecurityScheme(name string) *SecurityScheme {
+	for _, s := range o.securitySchemes {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
 func (o *Walker) Traverse() error {
-	for _, schema := range o.document.Components.Schemas.AdditionalProperties {
+	for _, schema := range o.document.GetComponents().GetSchemas().GetAdditionalProperties() {
 		// walk and resolve all refs
 		schemaModel, err := o.resolveSchemaOrRef(schema.Value, schema.Name)
 		if err != nil {
@@ -70,7 +87,20 @@ func (o *Walker) Traverse() error {
 		o.AddModel(schemaModel)
 	}
 
-	for _, path := range o.document.Paths.Path {
+	for _, scheme := range o.document.GetComponents().GetSecuritySchemes().GetAdditionalProperties() {
+		securityScheme, err := o.buildSecurityScheme(scheme)
+		if err != nil {
+			return err
+		}
+		o.securitySchemes = append(o.securitySchemes, securityScheme)
+	}
+	if len(o.securitySchemes) > 0 && o.source == nil {
+		// without it every operation would look like it needs no
+		// authentication
+		return errors.New("the spec has security schemes, so the walker needs the raw document to read security requirements from (see SetSource)")
+	}
+
+	for _, path := range o.document.GetPaths().GetPath() {
 		operations, err := o.buildOperationsFromPath(path)
 		if err != nil {
 			return err
@@ -81,6 +111,53 @@ func (o *Walker) Traverse() error {
 	return nil
 }
 
+func (o *Walker) buildSecurityScheme(scheme *openapi_v3.NamedSecuritySchemeOrReference) (*SecurityScheme, error) {
+	s := scheme.Value.GetSecurityScheme()
+	if s == nil {
+		// TODO: handle refs
+		return nil, fmt.Errorf("security scheme %v must not be a $ref", scheme.Name)
+	}
+
+	securityScheme := SecurityScheme{
+		Name:             scheme.Name,
+		Type:             s.Type,
+		Description:      s.Description,
+		ParamName:        s.Name,
+		In:               s.In,
+		Scheme:           strings.ToLower(s.Scheme),
+		BearerFormat:     s.BearerFormat,
+		Scopes:           map[string]string{},
+		OpenIDConnectURL: s.OpenIdConnectUrl,
+	}
+
+	switch s.Type {
+	case "apiKey":
+		if s.In != "header" && s.In != "query" && s.In != "cookie" {
+			return nil, fmt.Errorf("security scheme %v: apiKey must be in header, query or cookie, not %q", scheme.Name, s.In)
+		}
+	case "http":
+		if len(s.Scheme) == 0 {
+			return nil, fmt.Errorf("security scheme %v: http scheme is required", scheme.Name)
+		}
+	case "oauth2":
+		if s.Flows != nil {
+			for _, flow := range []*openapi_v3.OauthFlow{s.Flows.Implicit, s.Flows.Password, s.Flows.ClientCredentials, s.Flows.AuthorizationCode} {
+				if flow == nil || flow.Scopes == nil {
+					continue
+				}
+				for _, scope := range flow.Scopes.AdditionalProperties {
+					securityScheme.Scopes[scope.Name] = scope.Value
+				}
+			}
+		}
+	case "openIdConnect":
+	default:
+		return nil, fmt.Errorf("security scheme %v has unknown type %q", scheme.Name, s.Type)
+	}
+
+	return &securityScheme, nil
+}
+
 type handlerParams struct {
 	path   string
 	method string
@@ -149,23 +226,51 @@ func (o *Walker) buildOperationsFromPath(path *openapi_v3.NamedPathItem) ([]*Ope
 
 func (o *Walker) buildHandlersFromOp(op *openapi_v3.Operation, params handlerParams) (*Operation, error) {
 	operation := Operation{
-		Name:   utils.ToPascalCase(op.OperationId),
-		Method: params.method,
-		Path:   params.path,
+		OperationID: op.OperationId,
+		Name:        utils.ToPascalCase(op.OperationId),
+		Summary:     op.Summary,
+		Description: op.Description,
+		Deprecated:  op.Deprecated,
+		Tags:        op.Tags,
+		Security:    o.securityRequirements(params.path, params.method),
+		Method:      params.method,
+		Path:        params.path,
+	}
+
+	for _, requirement := range operation.Security {
+		for scheme := range requirement {
+			if o.FindSecurityScheme(scheme) == nil {
+				return nil, fmt.Errorf("operation %v requires unknown security scheme %v", op.OperationId, scheme)
+			}
+		}
 	}
 
 	parameters := []Parameter{}
 
 	if op.Parameters != nil {
-		for _, param := range op.Parameters {
+		for i, param := range op.Parameters {
 
 			// TODO: handle refs
 			if param.GetParameter() != nil {
 				p := param.GetParameter()
 				p2 := Parameter{
-					Name:     p.Name,
-					In:       p.In,
-					Required: p.Required,
+					Name:          p.Name,
+					In:            p.In,
+					Description:   p.Description,
+					Required:      p.Required,
+					Deprecated:    p.Deprecated,
+					Style:         p.Style,
+					AllowReserved: p.AllowReserved,
+				}
+
+				if len(p2.Style) == 0 {
+					p2.Style = defaultParameterStyle(p2.In)
+				}
+				// gnostic can't tell a missing explode from `explode: false`
+				if explode, ok := o.parameterExplode(params.path, params.method, i); ok {
+					p2.Explode = explode
+				} else {
+					p2.Explode = p.Explode || p2.Style == "form"
 				}
 
 				schemaModel, err := o.resolveSchemaOrRef(p.Schema, "")
@@ -173,8 +278,8 @@ func (o *Walker) buildHandlersFromOp(op *openapi_v3.Operation, params handlerPar
 					return nil, err
 				}
 
-				if schemaModel.IsObject() {
-					return nil, fmt.Errorf("parameter %v should not be an object", p2.Name)
+				if err := validateParameterStyle(p2, schemaModel); err != nil {
+					return nil, fmt.Errorf("operation %v: %v", op.OperationId, err)
 				}
 
 				p2.Schema = schemaModel
@@ -183,6 +288,7 @@ func (o *Walker) buildHandlersFromOp(op *openapi_v3.Operation, params handlerPar
 			}
 		}
 	}
+	operation.Parameters = parameters
 
 	if op.RequestBody != nil {
 		// TODO: references
@@ -207,24 +313,50 @@ func (o *Walker) buildHandlersFromOp(op *openapi_v3.Operation, params handlerPar
 				request.Body = schemaModel
 			}
 
+			if request.IsForm() {
+				if !request.Body.IsObject() {
+					return nil, fmt.Errorf("operation %v: %v request body must be an object", op.OperationId, mediaType.Name)
+				}
+				encoding, err := o.buildEncoding(mediaType, params)
+				if err != nil {
+					return nil, fmt.Errorf("operation %v: %v", op.OperationId, err)
+				}
+				request.Encoding = encoding
+			}
+
 			operation.Requests = append(operation.Requests, request)
 		}
 	}
 
-	if len(op.Responses.ResponseOrReference) > 0 {
-		for _, response := range op.Responses.ResponseOrReference {
+	responses := op.Responses.ResponseOrReference
+	if op.Responses.Default != nil {
+		responses = append(responses, &openapi_v3.NamedResponseOrReference{
+			Name:  "default",
+			Value: op.Responses.Default,
+		})
+	}
+	if len(responses) > 0 {
+		for _, response := range responses {
 			if resp := response.Value.GetResponse(); resp != nil {
+				headers, err := o.buildHeaders(resp.Headers)
+				if err != nil {
+					return nil, fmt.Errorf("response %v of operation %v: %v", response.Name, op.OperationId, err)
+				}
+
 				if resp.Content == nil {
 					operation.Responses = append(operation.Responses, Response{
-						StatusCode: response.Name,
+						StatusCode:  response.Name,
+						Description: resp.Description,
+						Headers:     headers,
 					})
 				} else {
 					for _, mediaType := range resp.Content.AdditionalProperties {
 						r := Response{
 							StatusCode:  response.Name,
+							Description: resp.Description,
 							ContentType: mediaType.Name,
+							Headers:     headers,
 						}
-						// TODO: if mediaTypeName is empty, don't add an extra "_"
 						// TODO: try to lookup response "name" from status code (e.g. 200 => OK)
 
 						schemaOrRef := mediaType.Value.Schema
@@ -253,6 +385,151 @@ func (o *Walker) buildHandlersFromOp(op *openapi_v3.Operation, params handlerPar
 	return &operation, nil
 }
 
+func defaultParameterStyle(in string) string {
+	switch in {
+	case "query", "cookie":
+		return "form"
+	}
+	return "simple"
+}
+
+// parameterStyles lists where each style may be used.
+var parameterStyles = map[string][]string{
+	"matrix":         {"path"},
+	"label":          {"path"},
+	"form":           {"query", "cookie"},
+	"simple":         {"path", "header"},
+	"spaceDelimited": {"query"},
+	"pipeDelimited":  {"query"},
+	"deepObject":     {"query"},
+}
+
+func validateParameterStyle(p Parameter, schema SchemaModel) error {
+	allowed, ok := parameterStyles[p.Style]
+	if !ok {
+		return fmt.Errorf("parameter %v has unknown style %q", p.Name, p.Style)
+	}
+
+	found := false
+	for _, in := range allowed {
+		found = found || in == p.In
+	}
+	if !found {
+		return fmt.Errorf("parameter %v: style %v can't be used in %v", p.Name, p.Style, p.In)
+	}
+
+	switch p.Style {
+	case "deepObject":
+		if !schema.IsObject() {
+			return fmt.Errorf("parameter %v: style deepObject requires an object", p.Name)
+		}
+	case "spaceDelimited", "pipeDelimited":
+		if schema.IsPrimitive() {
+			return fmt.Errorf("parameter %v: style %v requires an array or object", p.Name, p.Style)
+		}
+	}
+	return nil
+}
+
+// buildEncoding reads the encoding object of a form request body.
+func (o *Walker) buildEncoding(mediaType *openapi_v3.NamedMediaType, params handlerParams) ([]Encoding, error) {
+	encodings := []Encoding{}
+	if mediaType.Value.Encoding == nil {
+		return encodings, nil
+	}
+
+	for _, named := range mediaType.Value.Encoding.AdditionalProperties {
+		e := named.Value
+		headers, err := o.buildHeaders(e.Headers)
+		if err != nil {
+			return nil, fmt.Errorf("encoding of %v: %v", named.Name, err)
+		}
+
+		encoding := Encoding{
+			Property:      named.Name,
+			ContentType:   e.ContentType,
+			Headers:       headers,
+			Style:         e.Style,
+			AllowReserved: e.AllowReserved,
+		}
+		if len(encoding.Style) == 0 {
+			encoding.Style = "form"
+		}
+		if explode, ok := o.encodingExplode(params.path, params.method, mediaType.Name, named.Name); ok {
+			encoding.Explode = explode
+		} else {
+			encoding.Explode = e.Explode || encoding.Style == "form"
+		}
+
+		if _, ok := parameterStyles[encoding.Style]; !ok || encoding.Style == "matrix" || encoding.Style == "label" || encoding.Style == "simple" {
+			return nil, fmt.Errorf("encoding of %v has unsupported style %q", named.Name, encoding.Style)
+		}
+		encodings = append(encodings, encoding)
+	}
+	return encodings, nil
+}
+
+func componentHeaderPath(name string) string {
+	return fmt.Sprintf("#/components/headers/%s", name)
+}
+
+func (o *Walker) buildHeaders(headers *openapi_v3.HeadersOrReferences) ([]Header, error) {
+	result := []Header{}
+	if headers == nil {
+		return result, nil
+	}
+
+	for _, named := range headers.AdditionalProperties {
+		h := named.Value.GetHeader()
+		componentName := ""
+		if ref := named.Value.GetReference(); ref != nil {
+			var err error
+			h, componentName, err = o.resolveHeaderReference(ref)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if h == nil {
+			return nil, fmt.Errorf("header %v has no definition", named.Name)
+		}
+		if h.Schema == nil {
+			// TODO: headers described with `content` instead of `schema`
+			return nil, fmt.Errorf("header %v must have a schema", named.Name)
+		}
+
+		schemaModel, err := o.resolveSchemaOrRef(h.Schema, "")
+		if err != nil {
+			return nil, err
+		}
+		if schemaModel.IsObject() {
+			return nil, fmt.Errorf("header %v should not be an object", named.Name)
+		}
+
+		result = append(result, Header{
+			Component:   NewComponent(componentName),
+			Name:        named.Name,
+			Description: h.Description,
+			Required:    h.Required,
+			Deprecated:  h.Deprecated,
+			Schema:      schemaModel,
+		})
+	}
+	return result, nil
+}
+
+func (o *Walker) resolveHeaderReference(ref *openapi_v3.Reference) (*openapi_v3.Header, string, error) {
+	if headers := o.document.GetComponents().GetHeaders(); headers != nil {
+		for _, header := range headers.AdditionalProperties {
+			if strings.EqualFold(ref.XRef, componentHeaderPath(header.Name)) {
+				if h := header.Value.GetHeader(); h != nil {
+					return h, header.Name, nil
+				}
+			}
+		}
+	}
+	return nil, "", fmt.Errorf("could not resolve $ref: '%v'", ref.XRef)
+}
+
 func (o *Walker) resolveSchemaOrRef(schemaOrRef *openapi_v3.SchemaOrReference, componentName string) (SchemaModel, error) {
 	// schema reference
 	if ref := schemaOrRef.GetReference(); ref != nil {
@@ -267,17 +544,39 @@ func (o *Walker) resolveSchemaOrRef(schemaOrRef *openapi_v3.SchemaOrReference, c
 	return nil, errors.New("not sure what happened...")
 }
 
+func newCommonSchemaModel(schema *openapi_v3.Schema, componentName string) CommonSchemaModel {
+	model := CommonSchemaModel{
+		Component:   NewComponent(componentName),
+		Title:       schema.Title,
+		Description: schema.Description,
+		Type:        schema.Type,
+		Nullable:    schema.Nullable,
+		Deprecated:  schema.Deprecated,
+	}
+	for _, value := range schema.Enum {
+		var v interface{}
+		if err := yaml.Unmarshal([]byte(value.Yaml), &v); err == nil {
+			model.Enum = append(model.Enum, v)
+		}
+	}
+	if x := schema.Xml; x != nil {
+		model.XML = &XML{
+			Name:      x.Name,
+			Namespace: x.Namespace,
+			Prefix:    x.Prefix,
+			Attribute: x.Attribute,
+			Wrapped:   x.Wrapped,
+		}
+	}
+	return model
+}
+
 func (o *Walker) resolveSchema(schema *openapi_v3.Schema, componentName string) (SchemaModel, error) {
 	if schema.Type == "object" {
 		schemaModel := StructSchemaModel{
-			CommonSchemaModel: CommonSchemaModel{
-				Component: NewComponent(componentName),
-				Title:     schema.Title,
-				Type:      schema.Type,
-				Nullable:  schema.Nullable,
-			},
-			Required:   schema.Required,
-			Properties: make(map[string]SchemaModel),
+			CommonSchemaModel: newCommonSchemaModel(schema, componentName),
+			Required:          schema.Required,
+			Properties:        make(map[string]SchemaModel),
 		}
 		if schema.Properties != nil {
 			properties, err := o.buildProperties(schema.Properties)
@@ -326,30 +625,20 @@ func (o *Walker) resolveSchema(schema *openapi_v3.Schema, componentName string)
 			return nil, err
 		}
 		schemaModel := ArraySchemaModel{
-			CommonSchemaModel: CommonSchemaModel{
-				Component: NewComponent(componentName),
-				Title:     schema.Title,
-				Type:      schema.Type,
-				Nullable:  schema.Nullable,
-			},
-			Items:    itemModel,
-			MinItems: schema.MinItems,
-			MaxItems: schema.MaxItems,
+			CommonSchemaModel: newCommonSchemaModel(schema, componentName),
+			Items:             itemModel,
+			MinItems:          schema.MinItems,
+			MaxItems:          schema.MaxItems,
 		}
 
 		return &schemaModel, nil
 	}
 
 	schemaModel := PrimitiveSchemaModel{
-		CommonSchemaModel: CommonSchemaModel{
-			Component: NewComponent(componentName),
-			Title:     schema.Title,
-			Type:      schema.Type,
-			Nullable:  schema.Nullable,
-		},
-		Format:    schema.Format,
-		MinLength: schema.MinLength,
-		MaxLength: schema.MaxLength,
+		CommonSchemaModel: newCommonSchemaModel(schema, componentName),
+		Format:            schema.Format,
+		MinLength:         schema.MinLength,
+		MaxLength:         schema.MaxLength,
 	}
 
 	// TODO: not valid with other attributes (like properties, type)
@@ -442,7 +731,7 @@ func (o *Walker) resolveSchemaReference(ref *openapi_v3.Reference) (SchemaModel,
 		return existingModel, nil
 	}
 
-	for _, schema := range o.document.Components.Schemas.AdditionalProperties {
+	for _, schema := range o.document.GetComponents().GetSchemas().GetAdditionalProperties() {
 		// TODO: sub-refs? (e.g. #/components/schema/MyModel/properties/FooBar)
 		refPath := componentSchemaPath(schema.Name)
 		if strings.EqualFold(ref.XRef, refPath) {
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"testing"
+
+	openapi_v3 "github.com/googleapis/gnostic/OpenAPIv3"
+	"github.com/googleapis/gnostic/compiler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const securedSpec = `
+openapi: 3.0.0
+info: {title: t, version: "1"}
+security: [{key: []}]
+paths:
+	/items:
+		get:
+			operationId: listItems
+			responses: {"200": {description: ok}}
+		post:
+			operationId: addItem
+			security: []
+			responses: {"204": {description: added}}
+components:
+	securitySchemes:
+		key: {type: apiKey, in: header, name: X-API-Key}
+`
+
+func TestTraverseSecurity(t *testing.T) {
+	w := walk(t, securedSpec)
+
+	security := map[string][]SecurityRequirement{}
+	for _, op := range w.GetOperations() {
+		security[op.OperationID] = op.Security
+	}
+	assert.Equal(t, map[string][]SecurityRequirement{
+		"listItems": {{"key": []string{}}},
+		"addItem":   {},
+	}, security)
+}
+
+func TestTraverseSecurityWithoutSource(t *testing.T) {
+	info, _ := readSpec(t, securedSpec)
+	document, err := openapi_v3.NewDocument(info, compiler.NewContext("$root", nil))
+	require.NoError(t, err)
+
+	// the requirements can't be read, so rather than let every operation
+	// through unauthenticated the walker fails
+	w := NewWalker(document)
+	assert.ErrorContains(t, w.Traverse(), "SetSource")
+}
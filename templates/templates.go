@@ -0,0 +1,9 @@
+// Package templates holds the default generator templates. They are embedded
+// into the generator binary so it can run from any working directory; see
+// generator.LoadTemplates for how a user supplied directory overrides them.
+package templates
+
+import "embed"
+
+//go:embed *.tmpl
+var FS embed.FS
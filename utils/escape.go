@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// GoString renders s as a double quoted Go string literal.
+func GoString(s string) string {
+	return strconv.Quote(s)
+}
+
+// GoComment renders s as one or more // line comments. Carriage returns are
+// dropped and blank lines are kept as a bare "//" so paragraphs survive gofmt.
+func GoComment(s string) string {
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.TrimRight(s, "\n")
+	if len(s) == 0 {
+		return ""
+	}
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if len(line) == 0 {
+			lines[i] = "//"
+		} else {
+			lines[i] = "// " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// StructTag renders key/value pairs as a Go struct tag, including the
+// surrounding quotes, e.g. StructTag("json", "name,omitempty") returns
+// `json:"name,omitempty"`. Values are quoted so that they can contain any
+// character; if the tag ends up containing a backquote it is emitted as an
+// interpreted string literal instead of a raw one.
+func StructTag(pairs ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+":"+strconv.Quote(pairs[i+1]))
+	}
+	tag := strings.Join(parts, " ")
+
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// WrapText breaks lines longer than width at word boundaries. Existing line
+// breaks are kept so that lists and paragraphs in markdown descriptions
+// survive; a single word longer than width is left on its own line.
+func WrapText(s string, width int) string {
+	s = strings.ReplaceAll(s, "\r", "")
+	lines := strings.Split(s, "\n")
+
+	var wrapped []string
+	for _, line := range lines {
+		if len(line) <= width {
+			wrapped = append(wrapped, line)
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		current := ""
+		for _, word := range strings.Fields(line) {
+			if len(current) > 0 && len(current)+1+len(word) > width {
+				wrapped = append(wrapped, current)
+				current = ""
+			}
+			if len(current) == 0 {
+				current = indent + word
+			} else {
+				current += " " + word
+			}
+		}
+		wrapped = append(wrapped, current)
+	}
+	return strings.Join(wrapped, "\n")
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoString(t *testing.T) {
+	assert.Equal(t, `"a < b && c > \"d\""`, GoString(`a < b && c > "d"`))
+	assert.Equal(t, `"^[a-z]+\\d$"`, GoString(`^[a-z]+\d$`))
+}
+
+func TestGoComment(t *testing.T) {
+	cases := [][]string{
+		[]string{"", ""},
+		[]string{"one line", "// one line"},
+		[]string{"List<Case> & more\n", "// List<Case> & more"},
+		[]string{"first\r\n\r\nsecond  ", "// first\n//\n// second"},
+		[]string{"ends */ comment", "// ends */ comment"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c[1], GoComment(c[0]))
+	}
+}
+
+func TestStructTag(t *testing.T) {
+	assert.Equal(t, "`json:\"name,omitempty\"`", StructTag("json", "name,omitempty"))
+	assert.Equal(t, "`json:\"a\\\"b\" xml:\"c\"`", StructTag("json", `a"b`, "xml", "c"))
+	assert.Equal(t, "\"json:\\\"a`b\\\"\"", StructTag("json", "a`b"))
+}
+
+func TestWrapText(t *testing.T) {
+	cases := [][]string{
+		[]string{"short", "short"},
+		[]string{"the quick brown fox jumps", "the quick\nbrown fox\njumps"},
+		[]string{"- a list item that wraps\n- next", "- a list\nitem that\nwraps\n- next"},
+		[]string{"averyveryverylongword x", "averyveryverylongword\nx"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c[1], WrapText(c[0], 10))
+	}
+}
@@ -1,8 +1,10 @@
 package utils
 
 import (
+	"go/token"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 func ToPascalCase(s string) string {
@@ -29,3 +31,18 @@ func ToPascalCase(s string) string {
 	}
 	return n
 }
+
+// ToCamelCase is ToPascalCase with a lower case first letter, for use as a
+// parameter name. Names that would be Go keywords get a trailing underscore.
+func ToCamelCase(s string) string {
+	n := ToPascalCase(s)
+	r, size := utf8.DecodeRuneInString(n)
+	if size == 0 {
+		return n
+	}
+	n = string(unicode.ToLower(r)) + n[size:]
+	if token.IsKeyword(n) {
+		n += "_"
+	}
+	return n
+}
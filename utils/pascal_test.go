@@ -35,3 +35,16 @@ func TestToPascal(t *testing.T) {
 		assert.Equal(t, ToPascalCase(c[0]), c[1])
 	}
 }
+
+func TestToCamel(t *testing.T) {
+	cases := [][]string{
+		[]string{"x-next", "xNext"},
+		[]string{"Rate-Limit", "rateLimit"},
+		[]string{"type", "type_"},
+		[]string{"", ""},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c[1], ToCamelCase(c[0]))
+	}
+}